@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
+	"os"
+	"os/signal"
 	"short_link/config"
+	"short_link/internal/health"
 	"short_link/internal/logger"
+	"short_link/internal/metrics"
 	"short_link/internal/repository/cache"
 	"short_link/internal/repository/database"
 	"short_link/internal/service"
 	"short_link/internal/transport/rest"
+	"short_link/internal/worker"
+	"syscall"
+	"time"
 )
 
 func main() {
@@ -24,7 +32,7 @@ func main() {
 		logger.Error(err.Error())
 	}
 
-	defer pool.CloseDB()
+	metrics.RegisterDB(pool.GetDB(), "postgres")
 
 	cfgRedis := config.LoadRedisConfig()
 	rdb, err := cache.NewRedisConnect(cfgRedis)
@@ -33,13 +41,80 @@ func main() {
 		logger.Error(err.Error())
 	}
 
-	var r *database.LinkRepository = database.NewLinkRepository(pool.GetDB())
-	var s *service.LinkService = service.NewLinkService(r, rdb, logger)
-	var h *rest.HTTPHandler = rest.NewHTTPHanler(s)
-	var server *rest.HTTPServer = rest.NewServer(h)
+	generator, err := service.NewGenerator(config.LoadGeneratorConfig())
 
-	if err := server.StartServer(); err != nil {
+	if err != nil {
 		logger.Error(err.Error())
+		return
+	}
+
+	var r *database.LinkRepository = database.NewLinkRepository(pool.GetDB())
+
+	var clicks *worker.ClickWriter = worker.NewClickWriter(r, logger, worker.ClickWriterConfig{
+		QueueSize:     10000,
+		BatchSize:     500,
+		FlushInterval: 1 * time.Second,
+	})
+
+	go clicks.Start()
+
+	var s *service.LinkService = service.NewLinkService(r, rdb, generator, clicks, config.LoadAnalyticsConfig(), logger)
+	cfgHTTP := config.LoadHTTPConfig()
+	var h *rest.HTTPHandler = rest.NewHTTPHanler(s, config.LoadRedirectConfig(), cfgHTTP.TrustedProxies)
+
+	checker := health.NewChecker(2*time.Second,
+		health.Check{Name: "postgres", Fn: pool.Ping},
+		health.Check{Name: "redis", Fn: rdb.Ping},
+		health.Check{Name: "migrations", Fn: pool.CheckSchema},
+	)
+
+	var server *rest.HTTPServer = rest.NewServer(h, rest.NewHealthHandler(checker), cfgHTTP)
+
+	serverErr := make(chan error, 1)
+
+	go func() {
+		serverErr <- server.StartServer()
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case <-quit:
+		logger.Info("shutting down app...")
+
+		// Fail readiness first and keep serving for a while, so load
+		// balancers take the instance out of rotation before connections
+		// are refused.
+		checker.Shutdown()
+		time.Sleep(cfgHTTP.ShutdownDelay)
+	case err := <-serverErr:
+		if err != nil {
+			logger.Error(err.Error())
+		}
+	}
+
+	// In-flight requests are drained first: they may still queue clicks
+	// and need Postgres and Redis. The click writer then flushes its queue
+	// before the connections are closed.
+	ctx, cancel := context.WithTimeout(context.Background(), cfgHTTP.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("failed to drain http server: " + err.Error())
+	}
+
+	clicks.Stop()
+
+	if err := pool.CloseDB(); err != nil {
+		logger.Error("failed to close database: " + err.Error())
+	}
+
+	if rdb != nil {
+		if err := rdb.Close(); err != nil {
+			logger.Error("failed to close redis: " + err.Error())
+		}
 	}
 
+	logger.Info("app exited properly")
 }
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"errors"
+	"net/http"
 	"os"
 	"os/signal"
 	"short_link/config"
 	"short_link/internal/logger"
+	"short_link/internal/metrics"
+	"short_link/internal/repository/cache"
 	"short_link/internal/repository/database"
 	"short_link/internal/worker"
 	"syscall"
@@ -30,6 +34,19 @@ func main() {
 
 	defer pool.CloseDB()
 
+	metrics.RegisterDB(pool.GetDB(), "postgres")
+
+	metricsServer := &http.Server{
+		Addr:    config.LoadMetricsConfig().WorkerAddr,
+		Handler: metrics.Handler(),
+	}
+
+	go func() {
+		if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			workerLogger.Error("metrics server failed", logger.ErrorField(err))
+		}
+	}()
+
 	var r *database.LinkRepository = database.NewLinkRepository(pool.GetDB())
 
 	var c *worker.Cleaner = worker.NewCleaner(r, workerLogger, worker.CleanerConfig{
@@ -37,6 +54,36 @@ func main() {
 		BatchSize: 1000,
 	})
 
+	rdb, err := cache.NewRedisConnect(config.LoadRedisConfig())
+
+	if err != nil {
+		workerLogger.Error(err.Error())
+	}
+
+	var (
+		flusher    *worker.ClickFlusher
+		reconciler *worker.TopReconciler
+	)
+
+	if rdb != nil {
+		defer rdb.Close()
+
+		flusher = worker.NewClickFlusher(r, rdb, workerLogger, worker.ClickFlusherConfig{
+			Interval:  5 * time.Second,
+			BatchSize: 1000,
+		})
+
+		go flusher.Start()
+
+		reconciler = worker.NewTopReconciler(r, rdb, workerLogger, worker.TopReconcilerConfig{
+			Interval: 1 * time.Hour,
+			Lookback: 7 * 24 * time.Hour,
+			Settle:   1 * time.Minute,
+		})
+
+		go reconciler.Start()
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
@@ -47,5 +94,14 @@ func main() {
 
 	c.Stop()
 
+	if flusher != nil {
+		flusher.Stop()
+		reconciler.Stop()
+	}
+
+	if err := metricsServer.Close(); err != nil {
+		workerLogger.Error("failed to stop metrics server", logger.ErrorField(err))
+	}
+
 	workerLogger.Info("worker exited properly")
 }
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -27,6 +30,51 @@ type RedisConfig struct {
 	DB       int
 }
 
+type GeneratorConfig struct {
+	Strategy string
+	Length   int
+	Secret   string
+}
+
+type RedirectConfig struct {
+	// InactiveFallbackURL receives redirects to links that are not active
+	// yet. When empty such links answer 404.
+	InactiveFallbackURL string
+}
+
+type AnalyticsConfig struct {
+	// IPSalt is mixed into client ip hashes, so stored hashes cannot be
+	// reversed by hashing the whole address space.
+	IPSalt string
+}
+
+type MetricsConfig struct {
+	// WorkerAddr is where the worker serves /metrics. The app serves them
+	// on its own HTTP port.
+	WorkerAddr string
+}
+
+type HTTPConfig struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout limits regular responses. Exports extend it for their
+	// own stream.
+	WriteTimeout   time.Duration
+	IdleTimeout    time.Duration
+	MaxHeaderBytes int
+	// ShutdownDelay is how long the server keeps serving after SIGTERM with
+	// /readyz already failing, so load balancers stop routing to it first.
+	ShutdownDelay time.Duration
+	// ShutdownTimeout is how long in-flight requests may run after SIGTERM
+	// before their connections are closed.
+	ShutdownTimeout time.Duration
+	// TrustedProxies are the peers whose X-Forwarded-For header is
+	// believed. Requests from anyone else are identified by their own
+	// address.
+	TrustedProxies []netip.Prefix
+}
+
 func LoadDBConfig() DataBaseConfig {
 	return DataBaseConfig{
 		User:     getEnv("DB_USER"),
@@ -52,6 +100,66 @@ func LoadRedisConfig() RedisConfig {
 	}
 }
 
+func LoadGeneratorConfig() GeneratorConfig {
+	length, err := strconv.Atoi(getEnv("SHORT_CODE_LENGTH"))
+	if err != nil {
+		length = 6
+	}
+
+	strategy := getEnv("SHORT_CODE_GENERATOR")
+	if strategy == "" {
+		strategy = "base62"
+	}
+
+	return GeneratorConfig{
+		Strategy: strategy,
+		Length:   length,
+		Secret:   getEnv("SHORT_CODE_SECRET"),
+	}
+}
+
+func LoadRedirectConfig() RedirectConfig {
+	return RedirectConfig{
+		InactiveFallbackURL: getEnv("INACTIVE_LINK_FALLBACK_URL"),
+	}
+}
+
+func LoadAnalyticsConfig() AnalyticsConfig {
+	return AnalyticsConfig{
+		IPSalt: getEnv("CLICK_IP_SALT"),
+	}
+}
+
+func LoadMetricsConfig() MetricsConfig {
+	addr := getEnv("WORKER_METRICS_ADDR")
+	if addr == "" {
+		addr = ":9091"
+	}
+
+	return MetricsConfig{
+		WorkerAddr: addr,
+	}
+}
+
+func LoadHTTPConfig() HTTPConfig {
+	addr := getEnv("HTTP_ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+
+	return HTTPConfig{
+		Addr:              addr,
+		ReadTimeout:       getEnvDuration("HTTP_READ_TIMEOUT", 10*time.Second),
+		ReadHeaderTimeout: getEnvDuration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		WriteTimeout:      getEnvDuration("HTTP_WRITE_TIMEOUT", 15*time.Second),
+		IdleTimeout:       getEnvDuration("HTTP_IDLE_TIMEOUT", 60*time.Second),
+		MaxHeaderBytes:    getEnvInt("HTTP_MAX_HEADER_BYTES", 1<<20),
+		ShutdownDelay:     getEnvDuration("HTTP_SHUTDOWN_DELAY", 5*time.Second),
+		ShutdownTimeout:   getEnvDuration("HTTP_SHUTDOWN_TIMEOUT", 30*time.Second),
+		TrustedProxies:    getEnvPrefixes("TRUSTED_PROXIES"),
+	}
+}
+
 func getEnv(key string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -59,3 +167,43 @@ func getEnv(key string) string {
 
 	return ""
 }
+
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(getEnv(key))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
+
+// getEnvDuration reads a duration such as "15s" or "1m".
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(getEnv(key))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
+
+// getEnvPrefixes reads a comma-separated list of addresses and CIDR ranges.
+// Entries that do not parse are skipped.
+func getEnvPrefixes(key string) []netip.Prefix {
+	var prefixes []netip.Prefix
+
+	for _, value := range strings.Split(getEnv(key), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(value); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+
+	return prefixes
+}
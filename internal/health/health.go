@@ -0,0 +1,101 @@
+package health
+
+import (
+	"context"
+	"short_link/internal/model"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK           = "ok"
+	StatusReady        = "ready"
+	StatusNotReady     = "not_ready"
+	StatusShuttingDown = "shutting_down"
+	StatusUp           = "up"
+	StatusDown         = "down"
+)
+
+// Check is one dependency probed by the readiness check.
+type Check struct {
+	Name string
+	Fn   func(ctx context.Context) error
+}
+
+// Checker runs the readiness checks. Once shutdown has begun it reports
+// not ready without probing anything, so load balancers drain the instance
+// while it still serves requests.
+type Checker struct {
+	checks       []Check
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// Shutdown marks the instance as not ready for good.
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+}
+
+// Ready runs all checks concurrently, each bounded by the checker timeout,
+// and reports whether every one of them passed.
+func (c *Checker) Ready(ctx context.Context) (model.HealthDTO, bool) {
+	if c.shuttingDown.Load() {
+		return model.HealthDTO{Status: StatusShuttingDown}, false
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		ready  = true
+		result = model.HealthDTO{Checks: make(map[string]model.CheckDTO, len(c.checks))}
+	)
+
+	for _, check := range c.checks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Fn(checkCtx)
+
+			dto := model.CheckDTO{
+				Status:    StatusUp,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+
+			if err != nil {
+				dto.Status = StatusDown
+				dto.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			result.Checks[check.Name] = dto
+			if err != nil {
+				ready = false
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	result.Status = StatusReady
+	if !ready {
+		result.Status = StatusNotReady
+	}
+
+	return result, ready
+}
@@ -34,6 +34,10 @@ func String(key string, value string) zap.Field {
 	return zap.String(key, value)
 }
 
+func Int(key string, value int) zap.Field {
+	return zap.Int(key, value)
+}
+
 func ErrorField(err error) zap.Field {
 	return zap.Error(err)
 }
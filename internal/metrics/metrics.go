@@ -0,0 +1,88 @@
+// Package metrics defines the Prometheus metrics of the app and the worker.
+// Metrics are registered in the default registry and served by Handler.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "shortlink"
+
+var (
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of redirect and create requests by handler and status code.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"handler", "status"})
+
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Short link lookups in the Redis cache by result.",
+	}, []string{"result"})
+
+	GenerationRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "code_generation_retries_total",
+		Help:      "Generated short codes that collided with existing ones and were generated again.",
+	})
+
+	CleanerRunDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cleaner_run_duration_seconds",
+		Help:      "Duration of cleaner runs.",
+		Buckets:   prometheus.ExponentialBuckets(.01, 4, 8),
+	})
+
+	CleanerDeletedRows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cleaner_deleted_rows_total",
+		Help:      "Rows deleted by the cleaner by table.",
+	}, []string{"table"})
+)
+
+// RegisterDB exports the connection pool stats of db. Without a database
+// there is nothing to export, and the collector would fail on every scrape.
+func RegisterDB(db *sql.DB, name string) {
+	if db == nil {
+		return
+	}
+
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Instrument records the duration and status code of every request handled
+// by next under the given handler label.
+func Instrument(handler string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next(recorder, r)
+
+		RequestDuration.WithLabelValues(handler, strconv.Itoa(recorder.status)).Observe(time.Since(start).Seconds())
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
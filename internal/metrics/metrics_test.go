@@ -0,0 +1,13 @@
+package metrics
+
+import "testing"
+
+func TestRegisterDBWithoutDatabase(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("RegisterDB(nil) panicked: %v", r)
+		}
+	}()
+
+	RegisterDB(nil, "postgres")
+}
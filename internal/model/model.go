@@ -14,21 +14,247 @@ type ShortLink struct {
 	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
 	AccessedAt    *time.Time `json:"accessed_at,omitempty" db:"accessed_at"`
 	AccessedCount int        `json:"accessed_count" db:"accessed_count"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	MaxClicks     *int       `json:"max_clicks,omitempty" db:"max_clicks"`
+	PasswordHash  *string    `json:"-" db:"password_hash"`
+	ActiveFrom    *time.Time `json:"active_from,omitempty" db:"active_from"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	Disabled      bool       `json:"disabled" db:"disabled"`
+	BotCount      int        `json:"bot_count" db:"bot_count"`
+}
+
+// IsExpired reports whether the link has an expiration time that is already
+// in the past at the given moment.
+func (sl *ShortLink) IsExpired(now time.Time) bool {
+	return sl.ExpiresAt != nil && !sl.ExpiresAt.After(now)
+}
+
+// IsExhausted reports whether the link has used up all of its clicks. Bot
+// accesses count against the limit as well.
+func (sl *ShortLink) IsExhausted() bool {
+	return sl.MaxClicks != nil && sl.AccessedCount+sl.BotCount >= *sl.MaxClicks
+}
+
+// IsActive reports whether the activation time of the link has come.
+func (sl *ShortLink) IsActive(now time.Time) bool {
+	return sl.ActiveFrom == nil || !sl.ActiveFrom.After(now)
+}
+
+func (sl *ShortLink) IsDeleted() bool {
+	return sl.DeletedAt != nil
+}
+
+func (sl *ShortLink) IsProtected() bool {
+	return sl.PasswordHash != nil
+}
+
+type ClickEvent struct {
+	ShortURL       string    `json:"short_url"`
+	ClickedAt      time.Time `json:"clicked_at"`
+	Referrer       string    `json:"referrer,omitempty"`
+	UserAgent      string    `json:"user_agent,omitempty"`
+	IPHash         string    `json:"ip_hash,omitempty"`
+	IsBot          bool      `json:"is_bot,omitempty"`
+	DeviceType     string    `json:"device_type,omitempty"`
+	OSFamily       string    `json:"os_family,omitempty"`
+	BrowserFamily  string    `json:"browser_family,omitempty"`
+	ReferrerDomain string    `json:"referrer_domain,omitempty"`
+}
+
+type ClickCounter struct {
+	ShortURL   string
+	Clicks     int64
+	BotClicks  int64
+	AccessedAt *time.Time
+}
+
+type IdempotencyKey struct {
+	Key         string    `json:"key" db:"key"`
+	RequestHash string    `json:"request_hash" db:"request_hash"`
+	Response    []byte    `json:"response,omitempty" db:"response"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
 
 type LinkStatsDTO struct {
-	URL           string     `json:"url"`
-	ShortURL      string     `json:"short_url"`
-	CreatedAt     time.Time  `json:"create_at"`
-	AccessedAt    *time.Time `json:"accessed_at,omitempty"`
-	AccessedCount int        `json:"accessed_count"`
+	Id             int64           `json:"-"`
+	URL            string          `json:"url"`
+	ShortURL       string          `json:"short_url"`
+	CreatedAt      time.Time       `json:"create_at"`
+	AccessedAt     *time.Time      `json:"accessed_at,omitempty"`
+	AccessedCount  int             `json:"accessed_count"`
+	UniqueVisitors int64           `json:"unique_visitors"`
+	BotCount       int             `json:"bot_count"`
+	ExpiresAt      *time.Time      `json:"expires_at,omitempty"`
+	MaxClicks      *int            `json:"max_clicks,omitempty"`
+	Protected      bool            `json:"password_protected,omitempty"`
+	ActiveFrom     *time.Time      `json:"active_from,omitempty"`
+	Disabled       bool            `json:"disabled,omitempty"`
+	Referrers      []ClickShareDTO `json:"referrers,omitempty"`
+}
+
+type ReferrerStatsDTO struct {
+	ShortURL  string          `json:"short_url"`
+	From      time.Time       `json:"from"`
+	To        time.Time       `json:"to"`
+	Referrers []ClickShareDTO `json:"referrers"`
+}
+
+type TopLinkDTO struct {
+	Rank   int   `json:"rank"`
+	Clicks int64 `json:"clicks"`
+	LinkStatsDTO
+}
+
+type TopLinksDTO struct {
+	Window string       `json:"window"`
+	Links  []TopLinkDTO `json:"links"`
+}
+
+type ClickBucketDTO struct {
+	Time        time.Time `json:"time"`
+	HumanClicks int64     `json:"human_clicks"`
+	BotClicks   int64     `json:"bot_clicks"`
+}
+
+type ClickStatsDTO struct {
+	ShortURL       string           `json:"short_url"`
+	From           time.Time        `json:"from"`
+	To             time.Time        `json:"to"`
+	Interval       string           `json:"interval"`
+	TimeZone       string           `json:"time_zone"`
+	HumanClicks    int64            `json:"human_clicks"`
+	BotClicks      int64            `json:"bot_clicks"`
+	UniqueVisitors int64            `json:"unique_visitors"`
+	Buckets        []ClickBucketDTO `json:"buckets"`
+}
+
+type ClickShareDTO struct {
+	Name   string `json:"name"`
+	Clicks int64  `json:"clicks"`
 }
 
-type ErrorDTO struct {
-	Message string    `json:"message"`
-	Time    time.Time `json:"time"`
+type ClickBreakdownDTO struct {
+	ShortURL    string          `json:"short_url"`
+	From        time.Time       `json:"from"`
+	To          time.Time       `json:"to"`
+	HumanClicks int64           `json:"human_clicks"`
+	BotClicks   int64           `json:"bot_clicks"`
+	Devices     []ClickShareDTO `json:"devices"`
+	OS          []ClickShareDTO `json:"os"`
+	Browsers    []ClickShareDTO `json:"browsers"`
+}
+
+type LinkFilter struct {
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Disabled    *bool
+	MinClicks   *int
+	Host        string
+}
+
+type LinkListQuery struct {
+	Filter LinkFilter
+	Sort   string
+	Order  string
+	Cursor string
+	Limit  int
+}
+
+// LinkPage selects one page of the link listing. After is the position of
+// the last link of the previous page, nil for the first page.
+type LinkPage struct {
+	Sort  string
+	Desc  bool
+	Limit int
+	After *LinkCursor
+}
+
+// LinkCursor is the keyset position of a link in the listing: the value of
+// the sort column and the id that breaks ties. Only the field of the sort in
+// use is set.
+type LinkCursor struct {
+	Sort       string     `json:"s"`
+	Desc       bool       `json:"d,omitempty"`
+	CreatedAt  *time.Time `json:"c,omitempty"`
+	Clicks     int        `json:"n,omitempty"`
+	AccessedAt *time.Time `json:"a,omitempty"`
+	Id         int64      `json:"i"`
+}
+
+type LinkListDTO struct {
+	Items      []LinkStatsDTO `json:"items"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+type ClickStatsQuery struct {
+	From     string
+	To       string
+	Interval string
+	TimeZone string
+}
+
+type HealthDTO struct {
+	Status string              `json:"status"`
+	Checks map[string]CheckDTO `json:"checks,omitempty"`
+}
+
+type CheckDTO struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// ProblemDTO is an RFC 7807 problem details body. Code is a stable
+// machine-readable error code, Errors lists the request fields at fault.
+type ProblemDTO struct {
+	Type     string          `json:"type"`
+	Title    string          `json:"title"`
+	Status   int             `json:"status"`
+	Detail   string          `json:"detail,omitempty"`
+	Instance string          `json:"instance,omitempty"`
+	Code     string          `json:"code"`
+	Errors   []FieldErrorDTO `json:"errors,omitempty"`
+	Time     time.Time       `json:"time"`
+}
+
+type FieldErrorDTO struct {
+	Field   string `json:"field"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
 }
 
 type LinkDTO struct {
+	URL           string     `json:"url"`
+	Alias         string     `json:"alias,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	TTL           int64      `json:"ttl,omitempty"`
+	MaxClicks     *int       `json:"max_clicks,omitempty"`
+	Password      string     `json:"password,omitempty"`
+	ActiveFrom    *time.Time `json:"active_from,omitempty"`
+	ReuseExisting bool       `json:"reuse_existing,omitempty"`
+}
+
+type BatchLinkDTO struct {
+	URLs []string `json:"urls"`
+}
+
+// BatchLinkResultDTO is the outcome for one url of a batch. A failed item
+// carries the same code and field as the problem details of a single create.
+type BatchLinkResultDTO struct {
+	URL      string `json:"url"`
+	ShortURL string `json:"short_url,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Code     string `json:"code,omitempty"`
+	Field    string `json:"field,omitempty"`
+}
+
+type UpdateLinkDTO struct {
 	URL string `json:"url"`
 }
+
+type LinkHistoryDTO struct {
+	Id        int64     `json:"id"`
+	OldURL    string    `json:"old_url"`
+	NewURL    string    `json:"new_url"`
+	ChangedAt time.Time `json:"changed_at"`
+}
@@ -0,0 +1,55 @@
+// Package referrer reduces Referer headers to the site or app that sent the
+// visitor, so clicks from different pages of one site are counted together.
+package referrer
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	Direct = "direct"
+	Other  = "other"
+)
+
+// Domain returns the registrable domain of a Referer header by the public
+// suffix list: news.bbc.co.uk counts as bbc.co.uk and every user.github.io
+// as a site of its own. It returns Direct when the header is empty and Other
+// when it cannot be parsed. Android apps send android-app://<package> and
+// are reported as app:<package>.
+func Domain(referer string) string {
+	referer = strings.TrimSpace(referer)
+	if referer == "" {
+		return Direct
+	}
+
+	u, err := url.Parse(referer)
+	if err != nil {
+		return Other
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return Other
+	}
+
+	if u.Scheme == "android-app" {
+		return "app:" + host
+	}
+
+	if net.ParseIP(host) != nil {
+		return host
+	}
+
+	// Hosts that are a public suffix themselves, or have none, are kept
+	// as they are.
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+
+	return domain
+}
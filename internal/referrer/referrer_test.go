@@ -0,0 +1,36 @@
+package referrer
+
+import "testing"
+
+func TestDomain(t *testing.T) {
+	tests := []struct {
+		referer string
+		want    string
+	}{
+		{"", Direct},
+		{"   ", Direct},
+		{"https://www.google.com/search?q=x", "google.com"},
+		{"https://t.me/channel/42", "t.me"},
+		{"https://news.bbc.co.uk/sport", "bbc.co.uk"},
+		{"https://shop.example.co.jp/", "example.co.jp"},
+		{"https://www.city.shibuya.tokyo.jp/", "city.shibuya.tokyo.jp"},
+		{"https://alice.github.io/blog", "alice.github.io"},
+		{"https://bob.blogspot.com/2024/05/post.html", "bob.blogspot.com"},
+		{"https://github.io/", "github.io"},
+		{"https://M.VK.COM./feed", "vk.com"},
+		{"http://192.168.1.10:8080/page", "192.168.1.10"},
+		{"http://[::1]/", "::1"},
+		{"http://localhost:3000/", "localhost"},
+		{"android-app://org.telegram.messenger/", "app:org.telegram.messenger"},
+		{"not a url", Other},
+		{"https://%zz", Other},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.referer, func(t *testing.T) {
+			if got := Domain(tt.referer); got != tt.want {
+				t.Errorf("Domain(%q) = %q, want %q", tt.referer, got, tt.want)
+			}
+		})
+	}
+}
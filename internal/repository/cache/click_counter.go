@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"short_link/internal/model"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Click counters live under their own prefixes and the dirty set has a ':'
+// in its name. Short codes never contain ':', so none of these keys can
+// collide with a cached short code.
+const (
+	clickCountPrefix    = "click_count:"
+	clickBotCountPrefix = "click_bot_count:"
+	clickTimePrefix     = "click_at:"
+	dirtyClicksKey      = "click:dirty"
+)
+
+// Visit is one redirect to count in Redis. Cached is set when the redirect
+// was served from the cache, so its click is counted here instead of in
+// Postgres. Bots count neither as unique visitors nor in the leaderboard.
+type Visit struct {
+	ShortURL  string
+	VisitorID string
+	At        time.Time
+	Bot       bool
+	Cached    bool
+}
+
+// CountVisit does all Redis bookkeeping of one redirect in a single round
+// trip, as it runs on the redirect path.
+func (r *RedisClient) CountVisit(ctx context.Context, visit Visit) error {
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if visit.Cached {
+			incrClickCounter(ctx, pipe, visit.ShortURL, visit.At, visit.Bot)
+		}
+		if !visit.Bot {
+			addUniqueVisitor(ctx, pipe, visit.ShortURL, visit.VisitorID, visit.At)
+			incrTopLink(ctx, pipe, visit.ShortURL, visit.At)
+		}
+		return nil
+	})
+
+	return err
+}
+
+// incrClickCounter counts one access of shortURL and marks it for the next
+// flush to Postgres. Bot accesses are counted apart and do not move the last
+// access time.
+func incrClickCounter(ctx context.Context, pipe redis.Pipeliner, shortURL string, at time.Time, bot bool) {
+	if bot {
+		pipe.Incr(ctx, clickBotCountPrefix+shortURL)
+	} else {
+		pipe.Incr(ctx, clickCountPrefix+shortURL)
+		pipe.Set(ctx, clickTimePrefix+shortURL, at.UnixMilli(), 0)
+	}
+	pipe.SAdd(ctx, dirtyClicksKey, shortURL)
+}
+
+// DrainClickCounters takes up to limit pending counters out of Redis. The
+// caller owns the returned deltas and must give them back with
+// RestoreClickCounters if it fails to store them.
+func (r *RedisClient) DrainClickCounters(ctx context.Context, limit int) ([]model.ClickCounter, error) {
+	shortURLs, err := r.rdb.SPopN(ctx, dirtyClicksKey, int64(limit)).Result()
+
+	if err != nil {
+		return nil, fmt.Errorf("pop dirty click counters: %w", err)
+	}
+
+	if len(shortURLs) == 0 {
+		return nil, nil
+	}
+
+	counts := make([]*redis.StringCmd, len(shortURLs))
+	botCounts := make([]*redis.StringCmd, len(shortURLs))
+	times := make([]*redis.StringCmd, len(shortURLs))
+
+	_, err = r.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, shortURL := range shortURLs {
+			counts[i] = pipe.GetDel(ctx, clickCountPrefix+shortURL)
+			botCounts[i] = pipe.GetDel(ctx, clickBotCountPrefix+shortURL)
+			times[i] = pipe.GetDel(ctx, clickTimePrefix+shortURL)
+		}
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("take click counters: %w", err)
+	}
+
+	counters := make([]model.ClickCounter, 0, len(shortURLs))
+
+	for i, shortURL := range shortURLs {
+		// A code can stay in the dirty set after its counter was taken by
+		// the previous drain; there is nothing to flush for it then.
+		clicks, _ := counts[i].Int64()
+		botClicks, _ := botCounts[i].Int64()
+		if clicks <= 0 && botClicks <= 0 {
+			continue
+		}
+
+		counter := model.ClickCounter{
+			ShortURL:  shortURL,
+			Clicks:    clicks,
+			BotClicks: botClicks,
+		}
+
+		if ms, err := times[i].Int64(); err == nil {
+			accessedAt := time.UnixMilli(ms)
+			counter.AccessedAt = &accessedAt
+		}
+
+		counters = append(counters, counter)
+	}
+
+	return counters, nil
+}
+
+// RestoreClickCounters adds drained counters back, so they are retried on
+// the next flush.
+func (r *RedisClient) RestoreClickCounters(ctx context.Context, counters []model.ClickCounter) error {
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, c := range counters {
+			if c.Clicks > 0 {
+				pipe.IncrBy(ctx, clickCountPrefix+c.ShortURL, c.Clicks)
+			}
+			if c.BotClicks > 0 {
+				pipe.IncrBy(ctx, clickBotCountPrefix+c.ShortURL, c.BotClicks)
+			}
+			if c.AccessedAt != nil {
+				pipe.SetNX(ctx, clickTimePrefix+c.ShortURL, c.AccessedAt.UnixMilli(), 0)
+			}
+			pipe.SAdd(ctx, dirtyClicksKey, c.ShortURL)
+		}
+		return nil
+	})
+
+	return err
+}
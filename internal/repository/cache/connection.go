@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"short_link/config"
 	"time"
@@ -9,6 +10,8 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+var ErrCacheMiss = errors.New("cache miss")
+
 type RedisClient struct {
 	rdb *redis.Client
 }
@@ -37,13 +40,76 @@ func NewRedisConnect(cfg config.RedisConfig) (*RedisClient, error) {
 }
 
 func (r *RedisClient) Get(ctx context.Context, key string) (string, error) {
-	return r.rdb.Get(ctx, key).Result()
+	value, err := r.rdb.Get(ctx, key).Result()
+
+	if errors.Is(err, redis.Nil) {
+		return "", ErrCacheMiss
+	}
+
+	return value, err
 }
 
 func (r *RedisClient) Set(ctx context.Context, key, value string, expiration time.Duration) error {
 	return r.rdb.Set(ctx, key, value, expiration).Err()
 }
 
+func (r *RedisClient) Del(ctx context.Context, keys ...string) error {
+	return r.rdb.Del(ctx, keys...).Err()
+}
+
+// IncrWithExpire increments key and starts its expiration window on the
+// first increment, which makes it usable as a fixed-window attempt counter.
+func (r *RedisClient) IncrWithExpire(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pipe.ExpireNX(ctx, key, expiration)
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return incr.Val(), nil
+}
+
+// decrIfExists never recreates a key that expired in the meantime, which a
+// plain DECR would leave behind as a negative counter without a TTL.
+var decrIfExists = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return redis.call('DECR', KEYS[1])
+end
+return 0
+`)
+
+// DecrIfExists takes back one increment of a counter made by
+// IncrWithExpire, keeping its expiration window.
+func (r *RedisClient) DecrIfExists(ctx context.Context, key string) error {
+	return decrIfExists.Run(ctx, r.rdb, []string{key}).Err()
+}
+
+// SetMany writes all values in a single pipelined round trip.
+func (r *RedisClient) SetMany(ctx context.Context, values map[string]string, expiration time.Duration) error {
+	_, err := r.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, value := range values {
+			pipe.Set(ctx, key, value, expiration)
+		}
+		return nil
+	})
+
+	return err
+}
+
+func (r *RedisClient) Ping(ctx context.Context) error {
+	if r == nil {
+		return errors.New("redis is not connected")
+	}
+
+	return r.rdb.Ping(ctx).Err()
+}
+
 func (r *RedisClient) Close() error {
 	return r.rdb.Close()
 }
@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Short codes and aliases never contain ':', so every key the cache uses for
+// its own data must, or an alias could overwrite it through the redirect
+// cache that stores links under the bare short code.
+func TestInternalKeysAreNamespaced(t *testing.T) {
+	hour := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	keys := []string{
+		clickCountPrefix,
+		clickBotCountPrefix,
+		clickTimePrefix,
+		dirtyClicksKey,
+		linkInfoKey("abc"),
+		uniqueVisitorsKey("abc"),
+		uniqueVisitorsDayKey("abc", hour),
+		topHourKey(hour),
+		topWindowPrefix,
+	}
+
+	for _, key := range keys {
+		if !strings.Contains(key, ":") {
+			t.Errorf("internal key %q has no ':' and can collide with a short code", key)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"short_link/internal/model"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Link info is cached as JSON under its own prefix, apart from the redirect
+// target that is stored under the bare short code.
+const linkInfoPrefix = "info:"
+
+func linkInfoKey(shortURL string) string {
+	return linkInfoPrefix + shortURL
+}
+
+// GetLinkInfo returns the cached stats of a short link or ErrCacheMiss.
+func (r *RedisClient) GetLinkInfo(ctx context.Context, shortURL string) (*model.LinkStatsDTO, error) {
+	data, err := r.rdb.Get(ctx, linkInfoKey(shortURL)).Bytes()
+
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCacheMiss
+	} else if err != nil {
+		return nil, err
+	}
+
+	var info model.LinkStatsDTO
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+func (r *RedisClient) SetLinkInfo(ctx context.Context, info *model.LinkStatsDTO, expiration time.Duration) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	return r.rdb.Set(ctx, linkInfoKey(info.ShortURL), data, expiration).Err()
+}
+
+func (r *RedisClient) DelLinkInfo(ctx context.Context, shortURL string) error {
+	return r.rdb.Del(ctx, linkInfoKey(shortURL)).Err()
+}
@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"short_link/internal/model"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// The leaderboard keeps one sorted set of clicks per short code for every
+// hour. A window is a weighted union of its hours, cached for topWindowTTL.
+const (
+	topHourPrefix   = "top:h:"
+	topWindowPrefix = "top:w:"
+	TopRetention    = 8 * 24 * time.Hour
+	topWindowTTL    = 1 * time.Minute
+)
+
+func topHourKey(hour time.Time) string {
+	return topHourPrefix + hour.UTC().Format("2006010215")
+}
+
+// incrTopLink counts one click of shortURL in the hour of at.
+func incrTopLink(ctx context.Context, pipe redis.Pipeliner, shortURL string, at time.Time) {
+	key := topHourKey(at)
+
+	pipe.ZIncrBy(ctx, key, 1, shortURL)
+	pipe.ExpireNX(ctx, key, TopRetention)
+}
+
+// topWindowStore builds the union of the hourly sets that estimates the
+// clicks of the last hours before now. The window starts inside the oldest
+// hour, whose clicks are weighted by the part of it still in the window, as
+// the clicks of an hour are assumed to be spread evenly over it.
+func topWindowStore(hours int, now time.Time) *redis.ZStore {
+	current := now.UTC().Truncate(time.Hour)
+	elapsed := now.Sub(current)
+
+	store := &redis.ZStore{
+		Keys:    make([]string, hours+1),
+		Weights: make([]float64, hours+1),
+	}
+
+	for i := 0; i <= hours; i++ {
+		store.Keys[i] = topHourKey(current.Add(-time.Duration(i) * time.Hour))
+		store.Weights[i] = 1
+	}
+
+	store.Weights[hours] = 1 - float64(elapsed)/float64(time.Hour)
+
+	return store
+}
+
+// TopLinks returns up to limit short codes with most clicks over the given
+// number of hours before now. Clicks in the oldest hour of the window are
+// estimated, so the result is exact only to the hour.
+func (r *RedisClient) TopLinks(ctx context.Context, hours int, limit int, now time.Time) ([]model.ClickShareDTO, error) {
+	minute := now.UTC().Truncate(time.Minute)
+	dest := fmt.Sprintf("%s%d:%s", topWindowPrefix, hours, minute.Format("200601021504"))
+
+	exists, err := r.rdb.Exists(ctx, dest).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if exists == 0 {
+		_, err = r.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.ZUnionStore(ctx, dest, topWindowStore(hours, minute))
+			pipe.Expire(ctx, dest, topWindowTTL)
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	scores, err := r.rdb.ZRevRangeWithScores(ctx, dest, 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	top := make([]model.ClickShareDTO, len(scores))
+	for i, z := range scores {
+		top[i] = model.ClickShareDTO{Name: z.Member.(string), Clicks: int64(math.Round(z.Score))}
+	}
+
+	return top, nil
+}
+
+// MergeTopHour raises the clicks of one hour to counts where Redis has
+// fewer, which is how the leaderboard is reconciled with Postgres. Scores
+// are never lowered: the click writer may drop events, so Postgres can only
+// under-count.
+func (r *RedisClient) MergeTopHour(ctx context.Context, hour time.Time, counts map[string]int64) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	key := topHourKey(hour)
+
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		members := make([]redis.Z, 0, len(counts))
+		for shortURL, clicks := range counts {
+			members = append(members, redis.Z{Score: float64(clicks), Member: shortURL})
+		}
+
+		pipe.ZAddGT(ctx, key, members...)
+		pipe.ExpireAt(ctx, key, hour.Add(TopRetention))
+		return nil
+	})
+
+	return err
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTopWindowStore(t *testing.T) {
+	tests := []struct {
+		name       string
+		hours      int
+		now        time.Time
+		wantFirst  string
+		wantLast   string
+		wantOldest float64
+	}{
+		{
+			name:       "start of hour",
+			hours:      1,
+			now:        time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+			wantFirst:  "top:h:2024050110",
+			wantLast:   "top:h:2024050109",
+			wantOldest: 1,
+		},
+		{
+			name:       "quarter past",
+			hours:      1,
+			now:        time.Date(2024, 5, 1, 10, 15, 0, 0, time.UTC),
+			wantFirst:  "top:h:2024050110",
+			wantLast:   "top:h:2024050109",
+			wantOldest: 0.75,
+		},
+		{
+			name:       "day across midnight",
+			hours:      24,
+			now:        time.Date(2024, 5, 1, 0, 45, 0, 0, time.UTC),
+			wantFirst:  "top:h:2024050100",
+			wantLast:   "top:h:2024043000",
+			wantOldest: 0.25,
+		},
+		{
+			name:       "non-UTC now",
+			hours:      1,
+			now:        time.Date(2024, 5, 1, 13, 30, 0, 0, time.FixedZone("MSK", 3*60*60)),
+			wantFirst:  "top:h:2024050110",
+			wantLast:   "top:h:2024050109",
+			wantOldest: 0.5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := topWindowStore(tt.hours, tt.now)
+
+			if len(store.Keys) != tt.hours+1 || len(store.Weights) != tt.hours+1 {
+				t.Fatalf("got %d keys and %d weights, want %d", len(store.Keys), len(store.Weights), tt.hours+1)
+			}
+
+			if store.Keys[0] != tt.wantFirst || store.Keys[tt.hours] != tt.wantLast {
+				t.Errorf("keys span %s..%s, want %s..%s", store.Keys[0], store.Keys[tt.hours], tt.wantFirst, tt.wantLast)
+			}
+
+			for i, w := range store.Weights[:tt.hours] {
+				if w != 1 {
+					t.Errorf("hour %d weight = %v, want 1", i, w)
+				}
+			}
+
+			if math.Abs(store.Weights[tt.hours]-tt.wantOldest) > 1e-9 {
+				t.Errorf("oldest hour weight = %v, want %v", store.Weights[tt.hours], tt.wantOldest)
+			}
+		})
+	}
+}
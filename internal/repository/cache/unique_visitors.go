@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Unique visitors are estimated with HyperLogLogs: one per link and UTC day,
+// kept for UniqueVisitorsRetention, and one per link for all time. Each
+// takes at most 12 KB regardless of the number of visitors.
+const (
+	uniqueVisitorsPrefix    = "uv:"
+	UniqueVisitorsRetention = 400 * 24 * time.Hour
+)
+
+func uniqueVisitorsKey(shortURL string) string {
+	return uniqueVisitorsPrefix + shortURL
+}
+
+func uniqueVisitorsDayKey(shortURL string, day time.Time) string {
+	return uniqueVisitorsPrefix + shortURL + ":" + day.UTC().Format("20060102")
+}
+
+// addUniqueVisitor adds a visitor to the all-time and the daily estimate of
+// shortURL.
+func addUniqueVisitor(ctx context.Context, pipe redis.Pipeliner, shortURL, visitorID string, at time.Time) {
+	dayKey := uniqueVisitorsDayKey(shortURL, at)
+
+	pipe.PFAdd(ctx, uniqueVisitorsKey(shortURL), visitorID)
+	pipe.PFAdd(ctx, dayKey, visitorID)
+	pipe.ExpireNX(ctx, dayKey, UniqueVisitorsRetention)
+}
+
+// CountUniqueVisitors estimates the all-time unique visitors of several
+// short links in one round trip.
+func (r *RedisClient) CountUniqueVisitors(ctx context.Context, shortURLs ...string) (map[string]int64, error) {
+	counts := make([]*redis.IntCmd, len(shortURLs))
+
+	_, err := r.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, shortURL := range shortURLs {
+			counts[i] = pipe.PFCount(ctx, uniqueVisitorsKey(shortURL))
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	visitors := make(map[string]int64, len(shortURLs))
+	for i, shortURL := range shortURLs {
+		visitors[shortURL] = counts[i].Val()
+	}
+
+	return visitors, nil
+}
+
+// CountUniqueVisitorsBetween estimates the unique visitors of shortURL over
+// the UTC days from the day of from to the day of to. A visitor seen on
+// several days is counted once. Days past the retention are not counted.
+func (r *RedisClient) CountUniqueVisitorsBetween(ctx context.Context, shortURL string, from, to time.Time) (int64, error) {
+	if oldest := time.Now().Add(-UniqueVisitorsRetention); from.Before(oldest) {
+		from = oldest
+	}
+
+	keys := []string{}
+
+	for day := from.UTC().Truncate(24 * time.Hour); !day.After(to); day = day.AddDate(0, 0, 1) {
+		keys = append(keys, uniqueVisitorsDayKey(shortURL, day))
+	}
+
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	return r.rdb.PFCount(ctx, keys...).Result()
+}
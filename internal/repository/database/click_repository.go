@@ -0,0 +1,313 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"short_link/internal/model"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// InsertClickEvents stores a batch of click events in one statement. Events
+// of short links that no longer exist are skipped.
+func (r *LinkRepository) InsertClickEvents(ctx context.Context, tx *sql.Tx, events []model.ClickEvent) error {
+	query := `INSERT INTO click_events (short_link_id, clicked_at, referrer, user_agent, ip_hash,
+				device_type, os_family, browser_family, is_bot)
+			SELECT sl.id, e.clicked_at, NULLIF(e.referrer, ''), NULLIF(e.user_agent, ''), NULLIF(e.ip_hash, ''),
+				NULLIF(e.device_type, ''), NULLIF(e.os_family, ''), NULLIF(e.browser_family, ''), e.is_bot
+			FROM unnest($1::varchar[], $2::timestamptz[], $3::text[], $4::text[], $5::text[],
+				$6::varchar[], $7::varchar[], $8::varchar[], $9::boolean[])
+				AS e(short_url, clicked_at, referrer, user_agent, ip_hash, device_type, os_family, browser_family, is_bot)
+			INNER JOIN short_links AS sl
+			ON sl.short_url = e.short_url`
+
+	var (
+		shortURLs  = make([]string, len(events))
+		clickedAt  = make([]string, len(events))
+		referrers  = make([]string, len(events))
+		userAgents = make([]string, len(events))
+		ipHashes   = make([]string, len(events))
+		devices    = make([]string, len(events))
+		osFamilies = make([]string, len(events))
+		browsers   = make([]string, len(events))
+		bots       = make([]bool, len(events))
+	)
+
+	for i, e := range events {
+		shortURLs[i] = e.ShortURL
+		clickedAt[i] = e.ClickedAt.Format(time.RFC3339Nano)
+		referrers[i] = e.Referrer
+		userAgents[i] = e.UserAgent
+		ipHashes[i] = e.IPHash
+		devices[i] = e.DeviceType
+		osFamilies[i] = e.OSFamily
+		browsers[i] = e.BrowserFamily
+		bots[i] = e.IsBot
+	}
+
+	_, err := tx.ExecContext(ctx, query,
+		pq.Array(shortURLs), pq.Array(clickedAt), pq.Array(referrers), pq.Array(userAgents), pq.Array(ipHashes),
+		pq.Array(devices), pq.Array(osFamilies), pq.Array(browsers), pq.Array(bots))
+
+	if err != nil {
+		return fmt.Errorf("insert click events: %w", err)
+	}
+
+	return nil
+}
+
+// GetClickBreakdown counts clicks of a short link in [from, to) by device
+// type, OS family and browser family in one pass over click_events. Clicks
+// recorded before the user agent was classified are reported as "unknown".
+func (r *LinkRepository) GetClickBreakdown(ctx context.Context, tx *sql.Tx, shortLinkID int64, from, to time.Time) (devices, osFamilies, browsers []model.ClickShareDTO, err error) {
+	query := `SELECT CASE
+				WHEN GROUPING(device_type) = 0 THEN 'device'
+				WHEN GROUPING(os_family) = 0 THEN 'os'
+				ELSE 'browser'
+			END AS dimension,
+			COALESCE(device_type, os_family, browser_family, 'unknown') AS name,
+			count(*) AS clicks
+			FROM click_events
+			WHERE short_link_id = $1
+			AND clicked_at >= $2
+			AND clicked_at < $3
+			GROUP BY GROUPING SETS ((device_type), (os_family), (browser_family))
+			ORDER BY dimension, clicks DESC, name`
+
+	rows, err := tx.QueryContext(ctx, query, shortLinkID, from, to)
+
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Error: method get click breakdown: %w", err)
+	}
+
+	defer rows.Close()
+
+	devices, osFamilies, browsers = []model.ClickShareDTO{}, []model.ClickShareDTO{}, []model.ClickShareDTO{}
+
+	for rows.Next() {
+		var (
+			dimension string
+			share     model.ClickShareDTO
+		)
+
+		if err := rows.Scan(&dimension, &share.Name, &share.Clicks); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		switch dimension {
+		case "device":
+			devices = append(devices, share)
+		case "os":
+			osFamilies = append(osFamilies, share)
+		default:
+			browsers = append(browsers, share)
+		}
+	}
+
+	return devices, osFamilies, browsers, rows.Err()
+}
+
+// AddReferrerCounts adds the human clicks of a batch of click events to the
+// daily per-link referrer rollup. Days are counted in UTC.
+func (r *LinkRepository) AddReferrerCounts(ctx context.Context, tx *sql.Tx, events []model.ClickEvent) error {
+	query := `INSERT INTO referrer_daily (short_link_id, day, domain, clicks)
+			SELECT sl.id, (e.clicked_at AT TIME ZONE 'UTC')::date, e.domain, count(*)
+			FROM unnest($1::varchar[], $2::timestamptz[], $3::varchar[]) AS e(short_url, clicked_at, domain)
+			INNER JOIN short_links AS sl
+			ON sl.short_url = e.short_url
+			GROUP BY 1, 2, 3
+			ON CONFLICT (short_link_id, day, domain)
+			DO UPDATE SET clicks = referrer_daily.clicks + EXCLUDED.clicks`
+
+	var (
+		shortURLs = make([]string, 0, len(events))
+		clickedAt = make([]string, 0, len(events))
+		domains   = make([]string, 0, len(events))
+	)
+
+	for _, e := range events {
+		if e.IsBot {
+			continue
+		}
+		shortURLs = append(shortURLs, e.ShortURL)
+		clickedAt = append(clickedAt, e.ClickedAt.Format(time.RFC3339Nano))
+		domains = append(domains, e.ReferrerDomain)
+	}
+
+	if len(shortURLs) == 0 {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, query, pq.Array(shortURLs), pq.Array(clickedAt), pq.Array(domains))
+
+	if err != nil {
+		return fmt.Errorf("add referrer counts: %w", err)
+	}
+
+	return nil
+}
+
+// GetTopReferrers returns the referrer domains that sent most clicks to a
+// short link between the days of from and to, both inclusive.
+func (r *LinkRepository) GetTopReferrers(ctx context.Context, tx *sql.Tx, shortLinkID int64, from, to time.Time, limit int) ([]model.ClickShareDTO, error) {
+	query := `SELECT domain, sum(clicks) AS clicks
+			FROM referrer_daily
+			WHERE short_link_id = $1
+			AND day BETWEEN $2::date AND $3::date
+			GROUP BY domain
+			ORDER BY clicks DESC, domain
+			LIMIT $4`
+
+	rows, err := tx.QueryContext(ctx, query, shortLinkID, from.UTC().Format(time.DateOnly), to.UTC().Format(time.DateOnly), limit)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error: method get top referrers: %w", err)
+	}
+
+	defer rows.Close()
+
+	referrers := []model.ClickShareDTO{}
+
+	for rows.Next() {
+		var share model.ClickShareDTO
+
+		if err := rows.Scan(&share.Name, &share.Clicks); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		referrers = append(referrers, share)
+	}
+
+	return referrers, rows.Err()
+}
+
+// GetTopReferrersByShortURLs returns the all-time top referrers of several
+// short links at once, keyed by short code.
+func (r *LinkRepository) GetTopReferrersByShortURLs(ctx context.Context, tx *sql.Tx, shortURLs []string, limit int) (map[string][]model.ClickShareDTO, error) {
+	query := `SELECT short_url, domain, clicks
+			FROM (
+				SELECT sl.short_url, rd.domain, sum(rd.clicks) AS clicks,
+					row_number() OVER (PARTITION BY sl.short_url ORDER BY sum(rd.clicks) DESC, rd.domain) AS rank
+				FROM referrer_daily AS rd
+				INNER JOIN short_links AS sl
+				ON sl.id = rd.short_link_id
+				WHERE sl.short_url = ANY($1)
+				GROUP BY sl.short_url, rd.domain
+			) AS ranked
+			WHERE rank <= $2
+			ORDER BY short_url, rank`
+
+	rows, err := tx.QueryContext(ctx, query, pq.Array(shortURLs), limit)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error: method get top referrers by short urls: %w", err)
+	}
+
+	defer rows.Close()
+
+	referrers := make(map[string][]model.ClickShareDTO)
+
+	for rows.Next() {
+		var (
+			shortURL string
+			share    model.ClickShareDTO
+		)
+
+		if err := rows.Scan(&shortURL, &share.Name, &share.Clicks); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		referrers[shortURL] = append(referrers[shortURL], share)
+	}
+
+	return referrers, rows.Err()
+}
+
+// StreamClickEvents passes the click events of a short link in [from, to) to
+// fn in time order, one row at a time. An error from fn stops the stream and
+// is returned.
+func (r *LinkRepository) StreamClickEvents(ctx context.Context, tx *sql.Tx, shortLinkID int64, from, to time.Time, fn func(*model.ClickEvent) error) error {
+	query := `SELECT sl.short_url, ce.clicked_at, COALESCE(ce.referrer, ''), COALESCE(ce.user_agent, ''),
+			COALESCE(ce.device_type, ''), COALESCE(ce.os_family, ''), COALESCE(ce.browser_family, ''), ce.is_bot
+			FROM click_events AS ce
+			INNER JOIN short_links AS sl
+			ON sl.id = ce.short_link_id
+			WHERE ce.short_link_id = $1
+			AND ce.clicked_at >= $2
+			AND ce.clicked_at < $3
+			ORDER BY ce.clicked_at, ce.id`
+
+	rows, err := tx.QueryContext(ctx, query, shortLinkID, from, to)
+
+	if err != nil {
+		return fmt.Errorf("Error: method stream click events: %w", err)
+	}
+
+	defer rows.Close()
+
+	var event model.ClickEvent
+
+	for rows.Next() {
+		event = model.ClickEvent{}
+
+		err := rows.Scan(&event.ShortURL, &event.ClickedAt, &event.Referrer, &event.UserAgent,
+			&event.DeviceType, &event.OSFamily, &event.BrowserFamily, &event.IsBot)
+
+		if err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if err := fn(&event); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// CountHumanClicksByHour counts the human clicks in [from, to) per hour and
+// short code. Hours are truncated in UTC.
+func (r *LinkRepository) CountHumanClicksByHour(ctx context.Context, tx *sql.Tx, from, to time.Time) (map[time.Time]map[string]int64, error) {
+	query := `SELECT date_trunc('hour', ce.clicked_at AT TIME ZONE 'UTC') AS hour, sl.short_url, count(*)
+			FROM click_events AS ce
+			INNER JOIN short_links AS sl
+			ON sl.id = ce.short_link_id
+			WHERE ce.clicked_at >= $1
+			AND ce.clicked_at < $2
+			AND NOT ce.is_bot
+			GROUP BY 1, 2`
+
+	rows, err := tx.QueryContext(ctx, query, from, to)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error: method count human clicks by hour: %w", err)
+	}
+
+	defer rows.Close()
+
+	counts := make(map[time.Time]map[string]int64)
+
+	for rows.Next() {
+		var (
+			hour     time.Time
+			shortURL string
+			clicks   int64
+		)
+
+		if err := rows.Scan(&hour, &shortURL, &clicks); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		// The column is a timestamp without zone holding UTC wall time.
+		hour = time.Date(hour.Year(), hour.Month(), hour.Day(), hour.Hour(), 0, 0, 0, time.UTC)
+
+		if counts[hour] == nil {
+			counts[hour] = make(map[string]int64)
+		}
+		counts[hour][shortURL] = clicks
+	}
+
+	return counts, rows.Err()
+}
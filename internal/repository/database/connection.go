@@ -1,12 +1,15 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"short_link/config"
+	"short_link/migration"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
@@ -52,9 +55,63 @@ func (cp *ConectionPool) GetDB() *sql.DB {
 }
 
 func (cp *ConectionPool) CloseDB() error {
-	if cp.db != nil {
+	if cp != nil && cp.db != nil {
 		return cp.db.Close()
 	}
 
 	return nil
 }
+
+var ErrSchemaOutdated = errors.New("database schema is outdated")
+
+const undefinedTableCode = "42P01"
+
+func (cp *ConectionPool) Ping(ctx context.Context) error {
+	if cp == nil || cp.db == nil {
+		return ErrConnDB
+	}
+
+	return cp.db.PingContext(ctx)
+}
+
+// CheckSchema reports ErrSchemaOutdated unless golang-migrate has applied
+// the newest migration built into the binary. A newer version is fine: it
+// was applied for a newer release rolling out next to this one.
+func (cp *ConectionPool) CheckSchema(ctx context.Context) error {
+	if cp == nil || cp.db == nil {
+		return ErrConnDB
+	}
+
+	latest, err := migration.Latest()
+	if err != nil {
+		return fmt.Errorf("read embedded migrations: %w", err)
+	}
+
+	var (
+		version uint64
+		dirty   bool
+	)
+
+	err = cp.db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations`).Scan(&version, &dirty)
+
+	if errors.Is(err, sql.ErrNoRows) || isUndefinedTable(err) {
+		return fmt.Errorf("%w: no migration has been applied, want version %d", ErrSchemaOutdated, latest)
+	} else if err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+
+	if dirty {
+		return fmt.Errorf("%w: migration %d failed and left the schema dirty", ErrSchemaOutdated, version)
+	}
+
+	if version < latest {
+		return fmt.Errorf("%w: version %d, want %d", ErrSchemaOutdated, version, latest)
+	}
+
+	return nil
+}
+
+func isUndefinedTable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == undefinedTableCode
+}
@@ -6,10 +6,47 @@ import (
 	"errors"
 	"fmt"
 	"short_link/internal/model"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/lib/pq"
 )
 
+var ErrShortLinkExists = errors.New("short link already exists")
+
+const uniqueViolationCode = "23505"
+
+// shortLinkColumns is the column list scanned by scanShortLink.
+const shortLinkColumns = `id, id_url, short_url, created_at, accessed_at, accessed_count, expires_at, max_clicks, password_hash, active_from, deleted_at, disabled, bot_count`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanShortLink scans shortLinkColumns into shortLink, followed by any extra
+// columns selected after them.
+func scanShortLink(row rowScanner, shortLink *model.ShortLink, extra ...any) error {
+	dest := []any{
+		&shortLink.Id,
+		&shortLink.IdURL,
+		&shortLink.ShortURL,
+		&shortLink.CreatedAt,
+		&shortLink.AccessedAt,
+		&shortLink.AccessedCount,
+		&shortLink.ExpiresAt,
+		&shortLink.MaxClicks,
+		&shortLink.PasswordHash,
+		&shortLink.ActiveFrom,
+		&shortLink.DeletedAt,
+		&shortLink.Disabled,
+		&shortLink.BotCount,
+	}
+
+	return row.Scan(append(dest, extra...)...)
+}
+
 type LinkRepository struct {
 	db *sql.DB
 }
@@ -42,6 +79,41 @@ func (r *LinkRepository) CreateOriginalLink(ctx context.Context, tx *sql.Tx, ori
 	return &link, nil
 }
 
+// CreateOriginalLinks inserts all urls in one statement and returns their ids
+// keyed by url. Already existing urls are returned as well.
+func (r *LinkRepository) CreateOriginalLinks(ctx context.Context, tx *sql.Tx, originalUrls []string) (map[string]int64, error) {
+	query := `INSERT INTO links (url)
+	SELECT DISTINCT unnest($1::text[])
+	ON CONFLICT (url) DO UPDATE SET url = EXCLUDED.url
+	RETURNING id, url`
+
+	rows, err := tx.QueryContext(ctx, query, pq.Array(originalUrls))
+
+	if err != nil {
+		return nil, fmt.Errorf("Error when adding urls: %w", err)
+	}
+
+	defer rows.Close()
+
+	ids := make(map[string]int64, len(originalUrls))
+
+	for rows.Next() {
+		var link model.Link
+
+		if err := rows.Scan(&link.Id, &link.URL); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		ids[link.URL] = link.Id
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error when adding urls: %w", err)
+	}
+
+	return ids, nil
+}
+
 func (r *LinkRepository) ExistsOriginalLink(ctx context.Context, tx *sql.Tx, originalUrl string) (*model.Link, error) {
 	query := `SELECT id, url 
 			FROM links 
@@ -61,31 +133,138 @@ func (r *LinkRepository) ExistsOriginalLink(ctx context.Context, tx *sql.Tx, ori
 	return &link, nil
 }
 
-func (r *LinkRepository) CreateShortLink(ctx context.Context, tx *sql.Tx, shortUrl string, id_url int64) (*model.ShortLink, error) {
-	query := `INSERT INTO short_links (id_url, short_url)
-	VALUES ($1, $2)
-	RETURNING id, id_url, short_url, created_at, accessed_at, accessed_count`
+// NextShortLinkIDs reserves count ids from the short_links sequence, so short
+// codes can be derived from the id before the row is inserted.
+func (r *LinkRepository) NextShortLinkIDs(ctx context.Context, tx *sql.Tx, count int) ([]int64, error) {
+	query := `SELECT nextval(pg_get_serial_sequence('short_links', 'id'))
+			FROM generate_series(1, $1)`
+
+	rows, err := tx.QueryContext(ctx, query, count)
+
+	if err != nil {
+		return nil, fmt.Errorf("reserve short link ids: %w", err)
+	}
+
+	defer rows.Close()
+
+	ids := make([]int64, 0, count)
+
+	for rows.Next() {
+		var id int64
+
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// CreateShortLink inserts newLink. Id, IdURL and ShortURL are required, the
+// optional settings (ExpiresAt, MaxClicks, PasswordHash, ActiveFrom) are
+// stored as given. A taken short url is reported as ErrShortLinkExists
+// without aborting tx, so the caller may retry with another code.
+func (r *LinkRepository) CreateShortLink(ctx context.Context, tx *sql.Tx, newLink model.ShortLink) (*model.ShortLink, error) {
+	query := `INSERT INTO short_links (id, id_url, short_url, expires_at, max_clicks, password_hash, active_from)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT DO NOTHING
+	RETURNING ` + shortLinkColumns
 
 	var shortLink model.ShortLink
 
-	err := tx.QueryRowContext(ctx, query, id_url, shortUrl).Scan(
-		&shortLink.Id, &shortLink.IdURL, &shortLink.ShortURL, &shortLink.CreatedAt, &shortLink.AccessedAt, &shortLink.AccessedCount)
+	err := scanShortLink(tx.QueryRowContext(ctx, query,
+		newLink.Id, newLink.IdURL, newLink.ShortURL, newLink.ExpiresAt, newLink.MaxClicks, newLink.PasswordHash,
+		newLink.ActiveFrom), &shortLink)
 
 	if err != nil {
-		return nil, fmt.Errorf("Error when adding short url %s: %w", shortUrl, err)
+		if errors.Is(err, sql.ErrNoRows) || isUniqueViolation(err) {
+			return nil, ErrShortLinkExists
+		}
+		return nil, fmt.Errorf("Error when adding short url %s: %w", newLink.ShortURL, err)
 	}
 
 	return &shortLink, nil
 }
 
+// CreateShortLinks inserts short links in one statement. ids, shortUrls and
+// idUrls are matched by position.
+func (r *LinkRepository) CreateShortLinks(ctx context.Context, tx *sql.Tx, ids []int64, shortUrls []string, idUrls []int64) ([]model.ShortLink, error) {
+	query := `INSERT INTO short_links (id, id_url, short_url)
+	SELECT * FROM unnest($1::integer[], $2::integer[], $3::varchar[])
+	RETURNING ` + shortLinkColumns
+
+	rows, err := tx.QueryContext(ctx, query, pq.Array(ids), pq.Array(idUrls), pq.Array(shortUrls))
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrShortLinkExists
+		}
+		return nil, fmt.Errorf("Error when adding short urls: %w", err)
+	}
+
+	defer rows.Close()
+
+	shortLinks := make([]model.ShortLink, 0, len(shortUrls))
+
+	for rows.Next() {
+		var shortLink model.ShortLink
+
+		if err := scanShortLink(rows, &shortLink); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		shortLinks = append(shortLinks, shortLink)
+	}
+
+	if err := rows.Err(); err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrShortLinkExists
+		}
+		return nil, fmt.Errorf("Error when adding short urls: %w", err)
+	}
+
+	return shortLinks, nil
+}
+
+// ExistingShortLinks returns the subset of shortUrls that are already taken.
+func (r *LinkRepository) ExistingShortLinks(ctx context.Context, tx *sql.Tx, shortUrls []string) ([]string, error) {
+	query := `SELECT short_url
+			FROM short_links
+			WHERE short_url = ANY($1)`
+
+	rows, err := tx.QueryContext(ctx, query, pq.Array(shortUrls))
+
+	if err != nil {
+		return nil, fmt.Errorf("check short urls: %w", err)
+	}
+
+	defer rows.Close()
+
+	var existing []string
+
+	for rows.Next() {
+		var shortUrl string
+
+		if err := rows.Scan(&shortUrl); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		existing = append(existing, shortUrl)
+	}
+
+	return existing, rows.Err()
+}
+
 func (r *LinkRepository) ExistsShortLink(ctx context.Context, tx *sql.Tx, shortUrl string) (*model.ShortLink, error) {
-	query := `SELECT id, short_url 
+	query := `SELECT ` + shortLinkColumns + `
 			FROM short_links 
 			WHERE short_links.short_url = $1`
 
 	var shortLink model.ShortLink
 
-	err := tx.QueryRowContext(ctx, query, shortUrl).Scan(&shortLink.Id, &shortLink.ShortURL)
+	err := scanShortLink(tx.QueryRowContext(ctx, query, shortUrl), &shortLink)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -97,12 +276,187 @@ func (r *LinkRepository) ExistsShortLink(ctx context.Context, tx *sql.Tx, shortU
 	return &shortLink, nil
 }
 
-func (r *LinkRepository) GetOriginalLink(ctx context.Context, tx *sql.Tx, shortUrl string) (string, error) {
+// FindReusableShortLink returns the newest permanent short link pointing at
+// the given url, or nil when there is none.
+func (r *LinkRepository) FindReusableShortLink(ctx context.Context, tx *sql.Tx, id_url int64) (*model.ShortLink, error) {
+	query := `SELECT ` + shortLinkColumns + `
+			FROM short_links
+			WHERE id_url = $1
+			AND expires_at IS NULL
+			AND max_clicks IS NULL
+			AND password_hash IS NULL
+			AND (active_from IS NULL OR active_from <= CURRENT_TIMESTAMP)
+			AND deleted_at IS NULL
+			AND NOT disabled
+			ORDER BY created_at DESC
+			LIMIT 1`
+
+	var shortLink model.ShortLink
+
+	err := scanShortLink(tx.QueryRowContext(ctx, query, id_url), &shortLink)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("find reusable short link for url id %d: %w", id_url, err)
+	}
+
+	return &shortLink, nil
+}
+
+// LockShortLink returns the short link together with its current url and
+// locks the row until the end of the transaction.
+func (r *LinkRepository) LockShortLink(ctx context.Context, tx *sql.Tx, shortUrl string) (*model.ShortLink, string, error) {
+	query := `SELECT ` + shortLinkColumns + `,
+			(SELECT url FROM links WHERE links.id = short_links.id_url)
+			FROM short_links
+			WHERE short_url = $1
+			FOR UPDATE`
+
+	var (
+		shortLink model.ShortLink
+		url       string
+	)
+
+	err := scanShortLink(tx.QueryRowContext(ctx, query, shortUrl), &shortLink, &url)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("lock short link '%s': %w", shortUrl, err)
+	}
+
+	return &shortLink, url, nil
+}
+
+func (r *LinkRepository) UpdateShortLinkURL(ctx context.Context, tx *sql.Tx, id int64, id_url int64) error {
+	query := `UPDATE short_links
+			SET id_url = $2
+			WHERE id = $1`
+
+	_, err := tx.ExecContext(ctx, query, id, id_url)
+
+	if err != nil {
+		return fmt.Errorf("update destination of short link %d: %w", id, err)
+	}
+
+	return nil
+}
+
+func (r *LinkRepository) AddLinkHistory(ctx context.Context, tx *sql.Tx, shortLinkID int64, oldUrl, newUrl string) (*model.LinkHistoryDTO, error) {
+	query := `INSERT INTO short_link_history (short_link_id, old_url, new_url)
+			VALUES ($1, $2, $3)
+			RETURNING id, old_url, new_url, changed_at`
+
+	var entry model.LinkHistoryDTO
+
+	err := tx.QueryRowContext(ctx, query, shortLinkID, oldUrl, newUrl).Scan(&entry.Id, &entry.OldURL, &entry.NewURL, &entry.ChangedAt)
+
+	if err != nil {
+		return nil, fmt.Errorf("add history of short link %d: %w", shortLinkID, err)
+	}
+
+	return &entry, nil
+}
+
+func (r *LinkRepository) GetLinkHistory(ctx context.Context, tx *sql.Tx, shortLinkID int64) ([]model.LinkHistoryDTO, error) {
+	query := `SELECT id, old_url, new_url, changed_at
+			FROM short_link_history
+			WHERE short_link_id = $1
+			ORDER BY changed_at DESC, id DESC`
+
+	rows, err := tx.QueryContext(ctx, query, shortLinkID)
+
+	if err != nil {
+		return nil, fmt.Errorf("get history of short link %d: %w", shortLinkID, err)
+	}
+
+	defer rows.Close()
+
+	history := []model.LinkHistoryDTO{}
+
+	for rows.Next() {
+		var entry model.LinkHistoryDTO
+
+		if err := rows.Scan(&entry.Id, &entry.OldURL, &entry.NewURL, &entry.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		history = append(history, entry)
+	}
+
+	return history, rows.Err()
+}
+
+func (r *LinkRepository) GetLinkHistoryEntry(ctx context.Context, tx *sql.Tx, shortLinkID, historyID int64) (*model.LinkHistoryDTO, error) {
+	query := `SELECT id, old_url, new_url, changed_at
+			FROM short_link_history
+			WHERE short_link_id = $1 AND id = $2`
+
+	var entry model.LinkHistoryDTO
+
+	err := tx.QueryRowContext(ctx, query, shortLinkID, historyID).Scan(&entry.Id, &entry.OldURL, &entry.NewURL, &entry.ChangedAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get history entry %d: %w", historyID, err)
+	}
+
+	return &entry, nil
+}
+
+// SetShortLinkDeleted marks the short link as deleted or restores it. Deleted
+// links stay in the table, so the code remains reserved until the cleaner
+// purges them.
+func (r *LinkRepository) SetShortLinkDeleted(ctx context.Context, tx *sql.Tx, id int64, deleted bool) error {
+	query := `UPDATE short_links
+			SET deleted_at = CASE WHEN $2 THEN CURRENT_TIMESTAMP END
+			WHERE id = $1`
+
+	_, err := tx.ExecContext(ctx, query, id, deleted)
+
+	if err != nil {
+		return fmt.Errorf("update deleted state of short link %d: %w", id, err)
+	}
+
+	return nil
+}
+
+func (r *LinkRepository) SetShortLinkDisabled(ctx context.Context, tx *sql.Tx, id int64, disabled bool) error {
+	query := `UPDATE short_links
+			SET disabled = $2
+			WHERE id = $1`
+
+	_, err := tx.ExecContext(ctx, query, id, disabled)
+
+	if err != nil {
+		return fmt.Errorf("update disabled state of short link %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// GetOriginalLink counts one access and returns the destination url, or an
+// empty string when the link cannot be followed. Bot accesses go to
+// bot_count and do not move accessed_at, but every access, bot or not, uses
+// up max_clicks: the bot check is a heuristic and must not let a client open
+// a limited link for free.
+func (r *LinkRepository) GetOriginalLink(ctx context.Context, tx *sql.Tx, shortUrl string, bot bool) (string, error) {
 	query := `WITH updated AS (
         UPDATE short_links 
-        SET accessed_at = CURRENT_TIMESTAMP, 
-            accessed_count = accessed_count + 1
+        SET accessed_at = CASE WHEN $2 THEN accessed_at ELSE CURRENT_TIMESTAMP END,
+            accessed_count = accessed_count + CASE WHEN $2 THEN 0 ELSE 1 END,
+            bot_count = bot_count + CASE WHEN $2 THEN 1 ELSE 0 END
         WHERE short_url = $1
+          AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+          AND (max_clicks IS NULL OR accessed_count + bot_count < max_clicks)
+          AND (active_from IS NULL OR active_from <= CURRENT_TIMESTAMP)
+          AND deleted_at IS NULL
+          AND NOT disabled
         RETURNING id_url
     )
     SELECT l.url
@@ -111,7 +465,7 @@ func (r *LinkRepository) GetOriginalLink(ctx context.Context, tx *sql.Tx, shortU
 
 	var link string
 
-	err := tx.QueryRowContext(ctx, query, shortUrl).Scan(&link)
+	err := tx.QueryRowContext(ctx, query, shortUrl, bot).Scan(&link)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -122,62 +476,296 @@ func (r *LinkRepository) GetOriginalLink(ctx context.Context, tx *sql.Tx, shortU
 	return link, nil
 }
 
-func (r *LinkRepository) AccessedCountIncrement(ctx context.Context, tx *sql.Tx, shortURL string) error {
-	query := `UPDATE short_links
-			SET accessed_count = accessed_count + 1,
-			accessed_at = CURRENT_TIMESTAMP
-			WHERE short_url = $1`
+// AddAccessedCounts applies click counters collected in Redis: each link gets
+// its counted human and bot clicks added and its last human access moved
+// forward. Counters of
+// short links that no longer exist are skipped.
+func (r *LinkRepository) AddAccessedCounts(ctx context.Context, tx *sql.Tx, counters []model.ClickCounter) error {
+	query := `UPDATE short_links AS sl
+			SET accessed_count = sl.accessed_count + c.clicks,
+			bot_count = sl.bot_count + c.bot_clicks,
+			accessed_at = GREATEST(sl.accessed_at, NULLIF(c.accessed_at, '')::timestamptz)
+			FROM unnest($1::varchar[], $2::bigint[], $3::bigint[], $4::text[])
+				AS c(short_url, clicks, bot_clicks, accessed_at)
+			WHERE sl.short_url = c.short_url`
+
+	var (
+		shortURLs  = make([]string, len(counters))
+		clicks     = make([]int64, len(counters))
+		botClicks  = make([]int64, len(counters))
+		accessedAt = make([]string, len(counters))
+	)
+
+	for i, c := range counters {
+		shortURLs[i] = c.ShortURL
+		clicks[i] = c.Clicks
+		botClicks[i] = c.BotClicks
+		if c.AccessedAt != nil {
+			accessedAt[i] = c.AccessedAt.Format(time.RFC3339Nano)
+		}
+	}
 
-	_, err := tx.ExecContext(ctx, query, shortURL)
+	_, err := tx.ExecContext(ctx, query, pq.Array(shortURLs), pq.Array(clicks), pq.Array(botClicks), pq.Array(accessedAt))
 
 	if err != nil {
-		return fmt.Errorf(err.Error())
+		return fmt.Errorf("add accessed counts: %w", err)
 	}
 
 	return nil
 }
 
-func (r *LinkRepository) GetAllShortLink(ctx context.Context, tx *sql.Tx) ([]model.LinkStatsDTO, error) {
-	query := `SELECT l.url, sl.short_url, sl.created_at, sl.accessed_at, sl.accessed_count
+// linkStatsSelect selects the columns scanned by scanLinkStats.
+const linkStatsSelect = `SELECT l.url, sl.short_url, sl.created_at, sl.accessed_at, sl.accessed_count, sl.bot_count,
+			sl.expires_at, sl.max_clicks, sl.password_hash IS NOT NULL, sl.active_from, sl.disabled, sl.id
 			FROM short_links AS sl
-			INNER JOIN links AS l 
-			ON sl.id_url = l.id
-			ORDER BY sl.created_at DESC`
+			INNER JOIN links AS l
+			ON sl.id_url = l.id`
+
+func scanLinkStats(row rowScanner, linkStat *model.LinkStatsDTO) error {
+	return row.Scan(
+		&linkStat.URL,
+		&linkStat.ShortURL,
+		&linkStat.CreatedAt,
+		&linkStat.AccessedAt,
+		&linkStat.AccessedCount,
+		&linkStat.BotCount,
+		&linkStat.ExpiresAt,
+		&linkStat.MaxClicks,
+		&linkStat.Protected,
+		&linkStat.ActiveFrom,
+		&linkStat.Disabled,
+		&linkStat.Id,
+	)
+}
 
-	var linkStats []model.LinkStatsDTO
+// linkSortColumns maps the sorts of the link listing to the expressions they
+// order by. Links that were never opened sort as the oldest access, so the
+// keyset comparison never meets a NULL.
+var linkSortColumns = map[string]string{
+	"created":     "sl.created_at",
+	"clicks":      "sl.accessed_count",
+	"last_access": "COALESCE(sl.accessed_at, '-infinity'::timestamp)",
+}
 
-	rows, err := tx.QueryContext(ctx, query)
+// linkCursorParams are the placeholders of the cursor value for each sort.
+var linkCursorParams = map[string]string{
+	"created":     "$%d::timestamp",
+	"clicks":      "$%d::integer",
+	"last_access": "COALESCE($%d::timestamp, '-infinity'::timestamp)",
+}
+
+// linkFilterConditions turns filter into WHERE conditions over short_links
+// (sl) and links (l). The placeholders are numbered after the given args.
+func linkFilterConditions(filter model.LinkFilter, args []any) ([]string, []any) {
+	conditions := []string{"sl.deleted_at IS NULL"}
+
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.CreatedFrom != nil {
+		where("sl.created_at >= $%d", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		where("sl.created_at < $%d", *filter.CreatedTo)
+	}
+	if filter.Disabled != nil {
+		where("sl.disabled = $%d", *filter.Disabled)
+	}
+	if filter.MinClicks != nil {
+		where("sl.accessed_count >= $%d", *filter.MinClicks)
+	}
+	if filter.Host != "" {
+		where("l.host = $%d", filter.Host)
+	}
+
+	return conditions, args
+}
+
+// ListShortLinks returns one page of the short links matching filter. It
+// reads one row past the limit, so the caller can tell whether another page
+// follows.
+func (r *LinkRepository) ListShortLinks(ctx context.Context, tx *sql.Tx, filter model.LinkFilter, page model.LinkPage) ([]model.LinkStatsDTO, error) {
+	column, ok := linkSortColumns[page.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown link sort %q", page.Sort)
+	}
+
+	conditions, args := linkFilterConditions(filter, nil)
+
+	direction, compare := "ASC", ">"
+	if page.Desc {
+		direction, compare = "DESC", "<"
+	}
+
+	if page.After != nil {
+		var value any
+		switch page.Sort {
+		case "created":
+			value = page.After.CreatedAt
+		case "clicks":
+			value = page.After.Clicks
+		case "last_access":
+			value = page.After.AccessedAt
+		}
+
+		args = append(args, value, page.After.Id)
+		conditions = append(conditions, fmt.Sprintf("(%s, sl.id) %s (%s, $%d)",
+			column, compare, fmt.Sprintf(linkCursorParams[page.Sort], len(args)-1), len(args)))
+	}
+
+	args = append(args, page.Limit+1)
+
+	query := linkStatsSelect + `
+			WHERE ` + strings.Join(conditions, " AND ") + `
+			ORDER BY ` + column + ` ` + direction + `, sl.id ` + direction + `
+			LIMIT $` + strconv.Itoa(len(args))
+
+	rows, err := tx.QueryContext(ctx, query, args...)
 
 	if err != nil {
-		return nil, fmt.Errorf("Error: method get all links: %w", err)
+		return nil, fmt.Errorf("Error: method list links: %w", err)
 	}
 
 	defer rows.Close()
 
+	linkStats := make([]model.LinkStatsDTO, 0, page.Limit+1)
+
 	for rows.Next() {
 		var linkStat model.LinkStatsDTO
-		err := rows.Scan(
-			&linkStat.URL,
-			&linkStat.ShortURL,
-			&linkStat.CreatedAt,
-			&linkStat.AccessedAt,
-			&linkStat.AccessedCount,
-		)
 
-		if err != nil {
+		if err := scanLinkStats(rows, &linkStat); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		linkStats = append(linkStats, linkStat)
 	}
 
-	return linkStats, nil
+	return linkStats, rows.Err()
+}
+
+// GetLinkStatsByShortURLs returns the stats of the given short links that
+// were not deleted, keyed by short code.
+func (r *LinkRepository) GetLinkStatsByShortURLs(ctx context.Context, tx *sql.Tx, shortURLs []string) (map[string]model.LinkStatsDTO, error) {
+	query := linkStatsSelect + `
+			WHERE sl.short_url = ANY($1)
+			AND sl.deleted_at IS NULL`
+
+	rows, err := tx.QueryContext(ctx, query, pq.Array(shortURLs))
+
+	if err != nil {
+		return nil, fmt.Errorf("Error: method get links by short urls: %w", err)
+	}
+
+	defer rows.Close()
+
+	linkStats := make(map[string]model.LinkStatsDTO, len(shortURLs))
+
+	for rows.Next() {
+		var linkStat model.LinkStatsDTO
+
+		if err := scanLinkStats(rows, &linkStat); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		linkStats[linkStat.ShortURL] = linkStat
+	}
+
+	return linkStats, rows.Err()
 }
 
+// StreamShortLinks passes the short links matching filter to fn one row at a
+// time, straight from the result cursor, so exports of any size run in
+// constant memory. An error from fn stops the stream and is returned.
+func (r *LinkRepository) StreamShortLinks(ctx context.Context, tx *sql.Tx, filter model.LinkFilter, fn func(*model.LinkStatsDTO) error) error {
+	conditions, args := linkFilterConditions(filter, nil)
+
+	query := linkStatsSelect + `
+			WHERE ` + strings.Join(conditions, " AND ") + `
+			ORDER BY sl.created_at DESC, sl.id DESC`
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return fmt.Errorf("Error: method stream links: %w", err)
+	}
+
+	defer rows.Close()
+
+	var linkStat model.LinkStatsDTO
+
+	for rows.Next() {
+		linkStat = model.LinkStatsDTO{}
+
+		if err := scanLinkStats(rows, &linkStat); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if err := fn(&linkStat); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetClickTimeSeries counts clicks of a short link in [from, to) grouped into
+// the given buckets. Each bucket runs from its start to the start of the next
+// one, the last one to to; buckets without clicks are returned with zero.
+func (r *LinkRepository) GetClickTimeSeries(ctx context.Context, tx *sql.Tx, shortLinkID int64, from, to time.Time, bucketStarts []time.Time) ([]model.ClickBucketDTO, error) {
+	query := `WITH buckets AS (
+        SELECT bucket, lead(bucket, 1, $3::timestamptz) OVER (ORDER BY bucket) AS bucket_end
+        FROM unnest($4::timestamptz[]) AS bucket
+    )
+    SELECT b.bucket,
+        count(c.id) FILTER (WHERE NOT c.is_bot),
+        count(c.id) FILTER (WHERE c.is_bot)
+    FROM buckets AS b
+    LEFT JOIN click_events AS c
+    ON c.short_link_id = $1
+    AND c.clicked_at >= GREATEST(b.bucket, $2)
+    AND c.clicked_at < b.bucket_end
+    GROUP BY b.bucket
+    ORDER BY b.bucket`
+
+	starts := make([]string, len(bucketStarts))
+	for i, start := range bucketStarts {
+		starts[i] = start.Format(time.RFC3339Nano)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, shortLinkID, from, to, pq.Array(starts))
+
+	if err != nil {
+		return nil, fmt.Errorf("Error: method get click time series: %w", err)
+	}
+
+	defer rows.Close()
+
+	buckets := []model.ClickBucketDTO{}
+
+	for rows.Next() {
+		var bucket model.ClickBucketDTO
+
+		if err := rows.Scan(&bucket.Time, &bucket.HumanClicks, &bucket.BotClicks); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, rows.Err()
+}
+
+// FindExpiredLinks returns short links the cleaner may remove: expired by
+// their own expires_at, out of clicks, or soft-deleted longer than the
+// 30-day restore window. Links without expires_at never expire by idleness.
 func (r *LinkRepository) FindExpiredLinks(ctx context.Context, batchSize int, tx *sql.Tx) ([]string, error) {
 	query := `SELECT short_url
 			FROM short_links
-			WHERE accessed_at < CURRENT_TIMESTAMP - INTERVAL '24 hour'
+			WHERE expires_at <= CURRENT_TIMESTAMP
+			OR accessed_count + bot_count >= max_clicks
+			OR deleted_at < CURRENT_TIMESTAMP - INTERVAL '30 day'
 			LIMIT ($1)`
 
 	rows, err := tx.QueryContext(ctx, query, batchSize)
@@ -205,32 +793,105 @@ func (r *LinkRepository) FindExpiredLinks(ctx context.Context, batchSize int, tx
 	return expiredLinks, nil
 }
 
-func (r *LinkRepository) DeleteExpiredShortLinks(ctx context.Context, expLinks []string, tx *sql.Tx) error {
+func (r *LinkRepository) DeleteExpiredShortLinks(ctx context.Context, expLinks []string, tx *sql.Tx) (int64, error) {
 	query := `DELETE FROM short_links
-			WHERE short_url = ANY($1)
-			RETURNING id_url, short_url`
+			WHERE short_url = ANY($1)`
 
-	_, err := tx.ExecContext(ctx, query, pq.Array(expLinks))
+	result, err := tx.ExecContext(ctx, query, pq.Array(expLinks))
 
 	if err != nil {
-		return fmt.Errorf("Request execution error: %w", err)
+		return 0, fmt.Errorf("Request execution error: %w", err)
 	}
 
-	return nil
+	return result.RowsAffected()
 }
 
-func (r *LinkRepository) DeleteExpiredOriginalLinks(ctx context.Context, tx *sql.Tx) error {
+func (r *LinkRepository) DeleteExpiredOriginalLinks(ctx context.Context, tx *sql.Tx) (int64, error) {
 	query := `DELETE FROM links
 			WHERE id NOT IN(
 				SELECT id_url
 				FROM short_links
 				WHERE id_url IS NOT NULL)`
 
-	_, err := tx.ExecContext(ctx, query)
+	result, err := tx.ExecContext(ctx, query)
 
 	if err != nil {
-		return fmt.Errorf("Request execution error: %w", err)
+		return 0, fmt.Errorf("Request execution error: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
+
+// ClaimIdempotencyKey stores a new idempotency key and reports whether this
+// transaction owns it. A concurrent claim of the same key blocks until the
+// owning transaction finishes.
+func (r *LinkRepository) ClaimIdempotencyKey(ctx context.Context, tx *sql.Tx, key, requestHash string) (bool, error) {
+	query := `INSERT INTO idempotency_keys (key, request_hash)
+			VALUES ($1, $2)
+			ON CONFLICT (key) DO NOTHING
+			RETURNING key`
+
+	var claimed string
+
+	err := tx.QueryRowContext(ctx, query, key, requestHash).Scan(&claimed)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("claim idempotency key: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *LinkRepository) GetIdempotencyKey(ctx context.Context, tx *sql.Tx, key string) (*model.IdempotencyKey, error) {
+	query := `SELECT key, request_hash, response, created_at
+			FROM idempotency_keys
+			WHERE key = $1`
+
+	var idemKey model.IdempotencyKey
+
+	err := tx.QueryRowContext(ctx, query, key).Scan(&idemKey.Key, &idemKey.RequestHash, &idemKey.Response, &idemKey.CreatedAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get idempotency key: %w", err)
+	}
+
+	return &idemKey, nil
+}
+
+func (r *LinkRepository) SaveIdempotencyResponse(ctx context.Context, tx *sql.Tx, key string, response []byte) error {
+	query := `UPDATE idempotency_keys
+			SET response = $2
+			WHERE key = $1`
+
+	_, err := tx.ExecContext(ctx, query, key, string(response))
+
+	if err != nil {
+		return fmt.Errorf("save idempotency response: %w", err)
 	}
 
 	return nil
 }
+
+func (r *LinkRepository) DeleteExpiredIdempotencyKeys(ctx context.Context, tx *sql.Tx) (int64, error) {
+	query := `DELETE FROM idempotency_keys
+			WHERE created_at < CURRENT_TIMESTAMP - INTERVAL '24 hour'`
+
+	result, err := tx.ExecContext(ctx, query)
+
+	if err != nil {
+		return 0, fmt.Errorf("Request execution error: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,369 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"short_link/internal/logger"
+	"short_link/internal/model"
+	"short_link/internal/useragent"
+	"time"
+)
+
+const (
+	maxStatsBuckets      = 1000
+	defaultReferrerLimit = 10
+	maxLimit             = 100
+	// expandedReferrers is the number of top referrers added to each link of
+	// the listing when referrers are expanded.
+	expandedReferrers = 5
+)
+
+var statsIntervals = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+	"week": 7 * 24 * time.Hour,
+}
+
+// GetClickStats returns click counts of a short link bucketed by hour, day or
+// week. By default it covers the last 30 days by day in UTC. from and to
+// accept RFC 3339 timestamps or dates, which are read in the requested zone.
+func (s *LinkService) GetClickStats(ctx context.Context, shortURL string, query model.ClickStatsQuery) (*model.ClickStatsDTO, error) {
+	from, to, interval, loc, err := parseClickStatsQuery(query, time.Now())
+
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+
+	if err != nil {
+		s.Logger.Error("Failed to begin transaction",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err))
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.Logger.Error("Failed to rollback transaction",
+					logger.String("shortURL", shortURL),
+					logger.ErrorField(rbErr))
+			}
+		}
+	}()
+
+	shortLink, err := s.repo.ExistsShortLink(ctx, tx, shortURL)
+
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	} else if shortLink == nil || shortLink.IsDeleted() {
+		err = ErrLinkNotFound
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
+
+	buckets, err := s.repo.GetClickTimeSeries(ctx, tx, shortLink.Id, from, to, statsBuckets(from, to, interval, loc))
+
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.Logger.Error("Failed to commit transaction",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err))
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	stats := model.ClickStatsDTO{
+		ShortURL: shortURL,
+		From:     from.In(loc),
+		To:       to.In(loc),
+		Interval: interval,
+		TimeZone: loc.String(),
+		Buckets:  buckets,
+	}
+
+	for i := range stats.Buckets {
+		stats.Buckets[i].Time = stats.Buckets[i].Time.In(loc)
+		stats.HumanClicks += stats.Buckets[i].HumanClicks
+		stats.BotClicks += stats.Buckets[i].BotClicks
+	}
+
+	if s.cache != nil {
+		// Visitors are kept per UTC day, so the estimate covers whole days.
+		visitors, err := s.cache.CountUniqueVisitorsBetween(ctx, shortURL, from, to.Add(-time.Nanosecond))
+
+		if err != nil {
+			s.Logger.Error("Failed to count unique visitors",
+				logger.String("shortURL", shortURL),
+				logger.ErrorField(err))
+		}
+
+		stats.UniqueVisitors = visitors
+	}
+
+	return &stats, nil
+}
+
+// GetClickBreakdown returns the clicks of a short link split by device type,
+// OS family and browser family over the same window as GetClickStats.
+func (s *LinkService) GetClickBreakdown(ctx context.Context, shortURL string, query model.ClickStatsQuery) (*model.ClickBreakdownDTO, error) {
+	from, to, loc, err := parseStatsRange(query, time.Now())
+
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+
+	if err != nil {
+		s.Logger.Error("Failed to begin transaction",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err))
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.Logger.Error("Failed to rollback transaction",
+					logger.String("shortURL", shortURL),
+					logger.ErrorField(rbErr))
+			}
+		}
+	}()
+
+	shortLink, err := s.repo.ExistsShortLink(ctx, tx, shortURL)
+
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	} else if shortLink == nil || shortLink.IsDeleted() {
+		err = ErrLinkNotFound
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
+
+	breakdown := model.ClickBreakdownDTO{
+		ShortURL: shortURL,
+		From:     from.In(loc),
+		To:       to.In(loc),
+	}
+
+	breakdown.Devices, breakdown.OS, breakdown.Browsers, err = s.repo.GetClickBreakdown(ctx, tx, shortLink.Id, from, to)
+
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.Logger.Error("Failed to commit transaction",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err))
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Bot clicks are always recorded with the bot device type.
+	for _, device := range breakdown.Devices {
+		if device.Name == useragent.DeviceBot {
+			breakdown.BotClicks += device.Clicks
+		} else {
+			breakdown.HumanClicks += device.Clicks
+		}
+	}
+
+	return &breakdown, nil
+}
+
+// GetTopReferrers returns the referrer domains that sent most clicks to a
+// short link. The rollup is daily, so from and to select whole UTC days.
+// A zero limit means the default of 10.
+func (s *LinkService) GetTopReferrers(ctx context.Context, shortURL string, query model.ClickStatsQuery, limit int) (*model.ReferrerStatsDTO, error) {
+	if limit == 0 {
+		limit = defaultReferrerLimit
+	}
+
+	if limit < 1 || limit > maxLimit {
+		err := ErrLimitInvalid
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
+
+	from, to, loc, err := parseStatsRange(query, time.Now())
+
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+
+	if err != nil {
+		s.Logger.Error("Failed to begin transaction",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err))
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.Logger.Error("Failed to rollback transaction",
+					logger.String("shortURL", shortURL),
+					logger.ErrorField(rbErr))
+			}
+		}
+	}()
+
+	shortLink, err := s.repo.ExistsShortLink(ctx, tx, shortURL)
+
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	} else if shortLink == nil || shortLink.IsDeleted() {
+		err = ErrLinkNotFound
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
+
+	// to is exclusive, so the window ends with the day of its last instant.
+	referrers, err := s.repo.GetTopReferrers(ctx, tx, shortLink.Id, from, to.Add(-time.Nanosecond), limit)
+
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.Logger.Error("Failed to commit transaction",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err))
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &model.ReferrerStatsDTO{
+		ShortURL:  shortURL,
+		From:      from.In(loc),
+		To:        to.In(loc),
+		Referrers: referrers,
+	}, nil
+}
+
+func parseClickStatsQuery(query model.ClickStatsQuery, now time.Time) (from, to time.Time, interval string, loc *time.Location, err error) {
+	interval = query.Interval
+	if interval == "" {
+		interval = "day"
+	}
+
+	step, ok := statsIntervals[interval]
+	if !ok {
+		return from, to, "", nil, ErrStatsIntervalInvalid
+	}
+
+	from, to, loc, err = parseStatsRange(query, now)
+	if err != nil {
+		return from, to, "", nil, err
+	}
+
+	if to.Sub(from)/step >= maxStatsBuckets {
+		return from, to, "", nil, ErrStatsRangeInvalid
+	}
+
+	return from, to, interval, loc, nil
+}
+
+// statsBuckets returns the starts of the buckets of one interval that cover
+// [from, to) in loc. Buckets follow the wall clock of loc: across a DST change
+// a day or a week is an hour shorter or longer, and the hour repeated when
+// clocks go back is two buckets.
+func statsBuckets(from, to time.Time, interval string, loc *time.Location) []time.Time {
+	var starts []time.Time
+
+	for start := truncateBucket(from, interval, loc); start.Before(to); start = nextBucket(start, interval, loc) {
+		starts = append(starts, start)
+	}
+
+	return starts
+}
+
+func truncateBucket(t time.Time, interval string, loc *time.Location) time.Time {
+	t = t.In(loc)
+
+	switch interval {
+	case "hour":
+		// Going back from t itself keeps its offset, so a repeated hour is
+		// not confused with its twin.
+		return t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second -
+			time.Duration(t.Nanosecond()))
+	case "week":
+		// Weeks start on Monday, as in ISO 8601.
+		return time.Date(t.Year(), t.Month(), t.Day()-(int(t.Weekday())+6)%7, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+}
+
+func nextBucket(start time.Time, interval string, loc *time.Location) time.Time {
+	switch interval {
+	case "hour":
+		return truncateBucket(start.Add(time.Hour), interval, loc)
+	case "week":
+		return time.Date(start.Year(), start.Month(), start.Day()+7, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, loc)
+	}
+}
+
+// parseStatsRange resolves the time zone and the [from, to) window of a
+// stats query. The window defaults to the last 30 days.
+func parseStatsRange(query model.ClickStatsQuery, now time.Time) (from, to time.Time, loc *time.Location, err error) {
+	loc = time.UTC
+	if query.TimeZone != "" {
+		// "Local" is the zone of the server, which a client cannot mean.
+		if loc, err = time.LoadLocation(query.TimeZone); err != nil || query.TimeZone == "Local" {
+			return from, to, nil, fmt.Errorf("%w %q", ErrTimeZoneInvalid, query.TimeZone)
+		}
+	}
+
+	to = now
+	if query.To != "" {
+		if to, err = ParseTime(query.To, loc); err != nil {
+			return from, to, nil, WithField("to", err)
+		}
+	}
+
+	from = to.AddDate(0, 0, -30)
+	if query.From != "" {
+		if from, err = ParseTime(query.From, loc); err != nil {
+			return from, to, nil, WithField("from", err)
+		}
+	}
+
+	if !from.Before(to) {
+		return from, to, nil, ErrStatsRangeInvalid
+	}
+
+	return from, to, loc, nil
+}
+
+// ParseTime parses a query parameter given as an RFC 3339 timestamp or as a
+// YYYY-MM-DD date, which is read as midnight in loc.
+func ParseTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation(time.DateOnly, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w, got %q", ErrTimeInvalid, value)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"errors"
+	"short_link/internal/model"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestStatsBucketsAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		interval string
+		want     []string
+	}{
+		{
+			name:     "hours when clocks go forward",
+			from:     time.Date(2024, 3, 31, 1, 0, 0, 0, berlin),
+			to:       time.Date(2024, 3, 31, 4, 0, 0, 0, berlin),
+			interval: "hour",
+			want:     []string{"2024-03-31T01:00:00+01:00", "2024-03-31T03:00:00+02:00"},
+		},
+		{
+			name:     "hours when clocks go back",
+			from:     time.Date(2024, 10, 27, 1, 0, 0, 0, berlin),
+			to:       time.Date(2024, 10, 27, 4, 0, 0, 0, berlin),
+			interval: "hour",
+			want: []string{
+				"2024-10-27T01:00:00+02:00",
+				"2024-10-27T02:00:00+02:00",
+				"2024-10-27T02:00:00+01:00",
+				"2024-10-27T03:00:00+01:00",
+			},
+		},
+		{
+			name:     "days across the change",
+			from:     time.Date(2024, 3, 30, 12, 0, 0, 0, berlin),
+			to:       time.Date(2024, 4, 1, 12, 0, 0, 0, berlin),
+			interval: "day",
+			want: []string{
+				"2024-03-30T00:00:00+01:00",
+				"2024-03-31T00:00:00+01:00",
+				"2024-04-01T00:00:00+02:00",
+			},
+		},
+		{
+			name:     "weeks start on Monday",
+			from:     time.Date(2024, 3, 27, 0, 0, 0, 0, berlin),
+			to:       time.Date(2024, 4, 8, 0, 0, 0, 0, berlin),
+			interval: "week",
+			want:     []string{"2024-03-25T00:00:00+01:00", "2024-04-01T00:00:00+02:00"},
+		},
+		{
+			name:     "from inside a bucket",
+			from:     time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+			to:       time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			interval: "hour",
+			want:     []string{"2024-05-01T12:00:00+02:00", "2024-05-01T13:00:00+02:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := statsBuckets(tt.from, tt.to, tt.interval, berlin)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d buckets %v, want %v", len(got), got, tt.want)
+			}
+
+			for i, start := range got {
+				if s := start.Format(time.RFC3339); s != tt.want[i] {
+					t.Errorf("bucket %d = %s, want %s", i, s, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2024-05-01T10:00:00Z", want: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{value: "2024-05-01T10:00:00+02:00", want: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)},
+		{value: "2024-05-01", want: time.Date(2024, 5, 1, 0, 0, 0, 0, moscow)},
+		{value: "2024-13-01", wantErr: true},
+		{value: "01.05.2024", wantErr: true},
+		{value: "2024-05-01 10:00", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseTime(tt.value, moscow)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrTimeInvalid) {
+					t.Errorf("ParseTime(%q) error = %v, want ErrTimeInvalid", tt.value, err)
+				}
+				return
+			}
+
+			if err != nil || !got.Equal(tt.want) {
+				t.Errorf("ParseTime(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStatsRange(t *testing.T) {
+	now := time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		query    model.ClickStatsQuery
+		wantFrom time.Time
+		wantTo   time.Time
+		wantLoc  string
+		wantErr  error
+	}{
+		{
+			name:     "defaults to the last 30 days in UTC",
+			wantFrom: now.AddDate(0, 0, -30),
+			wantTo:   now,
+			wantLoc:  "UTC",
+		},
+		{
+			name:     "dates are read in the zone",
+			query:    model.ClickStatsQuery{From: "2024-05-01", To: "2024-05-08", TimeZone: "Europe/Berlin"},
+			wantFrom: time.Date(2024, 4, 30, 22, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2024, 5, 7, 22, 0, 0, 0, time.UTC),
+			wantLoc:  "Europe/Berlin",
+		},
+		{
+			name:     "from defaults to 30 days before to",
+			query:    model.ClickStatsQuery{To: "2024-05-08T00:00:00Z"},
+			wantFrom: time.Date(2024, 4, 8, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2024, 5, 8, 0, 0, 0, 0, time.UTC),
+			wantLoc:  "UTC",
+		},
+		{
+			name:    "unknown zone",
+			query:   model.ClickStatsQuery{TimeZone: "Mars/Olympus"},
+			wantErr: ErrTimeZoneInvalid,
+		},
+		{
+			name:    "server zone",
+			query:   model.ClickStatsQuery{TimeZone: "Local"},
+			wantErr: ErrTimeZoneInvalid,
+		},
+		{
+			name:    "bad from",
+			query:   model.ClickStatsQuery{From: "yesterday"},
+			wantErr: ErrTimeInvalid,
+		},
+		{
+			name:    "from after to",
+			query:   model.ClickStatsQuery{From: "2024-05-08", To: "2024-05-01"},
+			wantErr: ErrStatsRangeInvalid,
+		},
+		{
+			name:    "empty range",
+			query:   model.ClickStatsQuery{From: "2024-05-01", To: "2024-05-01"},
+			wantErr: ErrStatsRangeInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, loc, err := parseStatsRange(tt.query, now)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) || loc.String() != tt.wantLoc {
+				t.Errorf("got [%v, %v) in %s, want [%v, %v) in %s", from, to, loc, tt.wantFrom, tt.wantTo, tt.wantLoc)
+			}
+		})
+	}
+}
+
+func TestParseClickStatsQuery(t *testing.T) {
+	now := time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		query        model.ClickStatsQuery
+		wantInterval string
+		wantErr      error
+	}{
+		{name: "defaults to day", wantInterval: "day"},
+		{name: "hour", query: model.ClickStatsQuery{Interval: "hour", From: "2024-05-30"}, wantInterval: "hour"},
+		{name: "unknown interval", query: model.ClickStatsQuery{Interval: "month"}, wantErr: ErrStatsIntervalInvalid},
+		{name: "too many buckets", query: model.ClickStatsQuery{Interval: "hour", From: "2024-01-01"}, wantErr: ErrStatsRangeInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, interval, _, err := parseClickStatsQuery(tt.query, now)
+
+			if !errors.Is(err, tt.wantErr) || interval != tt.wantInterval {
+				t.Errorf("got %q, %v; want %q, %v", interval, err, tt.wantInterval, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"short_link/internal/logger"
+	"short_link/internal/model"
+	"short_link/internal/referrer"
+	"short_link/internal/repository/cache"
+	"short_link/internal/useragent"
+	"time"
+)
+
+// ClickRecorder accepts click events for asynchronous storage. Record must
+// not block; it reports false when the event was dropped.
+type ClickRecorder interface {
+	Record(event model.ClickEvent) bool
+}
+
+// Visit describes the request that followed a short link.
+type Visit struct {
+	ShortURL       string
+	Referrer       string
+	UserAgent      string
+	AcceptLanguage string
+	ClientIP       string
+}
+
+// IsBot reports whether the visit was made by a crawler, a link previewer or
+// an HTTP library rather than a person.
+func (v Visit) IsBot() bool {
+	return useragent.IsBot(v.UserAgent, v.AcceptLanguage)
+}
+
+// RecordClick queues a click event for a successful redirect. The client ip
+// is stored only as a salted hash, the user agent is classified right away so
+// reports can group by device, OS and browser. Dropped events are counted and
+// reported by the recorder itself.
+func (s *LinkService) RecordClick(ctx context.Context, visit Visit) {
+	now := time.Now()
+	bot := visit.IsBot()
+
+	if s.clicks == nil {
+		return
+	}
+
+	agent := useragent.Parse(visit.UserAgent)
+	if bot {
+		agent.DeviceType = useragent.DeviceBot
+	}
+
+	event := model.ClickEvent{
+		ShortURL:       visit.ShortURL,
+		ClickedAt:      now,
+		Referrer:       visit.Referrer,
+		UserAgent:      visit.UserAgent,
+		IPHash:         s.hashIP(visit.ClientIP),
+		IsBot:          bot,
+		DeviceType:     agent.DeviceType,
+		OSFamily:       agent.OS,
+		BrowserFamily:  agent.Browser,
+		ReferrerDomain: referrer.Domain(visit.Referrer),
+	}
+
+	s.clicks.Record(event)
+}
+
+func (s *LinkService) hashIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(s.analyticsCfg.IPSalt + ip))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// countVisit does the Redis bookkeeping of a successful redirect in one round
+// trip: the click itself when cached is set, since cache hits never touch
+// Postgres, and for people the unique visitor estimate and the leaderboard.
+// A visitor is identified by a salted hash of ip and user agent, so refreshes
+// by the same person are counted once. A failure only loses this one visit
+// in Redis; the leaderboard catches up at the next reconciliation.
+func (s *LinkService) countVisit(ctx context.Context, visit Visit, cached bool) {
+	bot := visit.IsBot()
+
+	if s.cache == nil || (bot && !cached) {
+		return
+	}
+
+	sum := sha256.Sum256([]byte(s.analyticsCfg.IPSalt + visit.ClientIP + "\x00" + visit.UserAgent))
+
+	err := s.cache.CountVisit(ctx, cache.Visit{
+		ShortURL:  visit.ShortURL,
+		VisitorID: hex.EncodeToString(sum[:]),
+		At:        time.Now(),
+		Bot:       bot,
+		Cached:    cached,
+	})
+
+	if err != nil {
+		s.Logger.Error("Failed to count visit in cache",
+			logger.String("shortURL", visit.ShortURL),
+			logger.ErrorField(err),
+		)
+	}
+}
+
+// uniqueVisitors estimates all-time unique visitors of the given links. It
+// returns nil when Redis is unavailable, so the counts read as zero.
+func (s *LinkService) uniqueVisitors(ctx context.Context, shortURLs ...string) map[string]int64 {
+	if s.cache == nil || len(shortURLs) == 0 {
+		return nil
+	}
+
+	visitors, err := s.cache.CountUniqueVisitors(ctx, shortURLs...)
+
+	if err != nil {
+		s.Logger.Error("Failed to count unique visitors",
+			logger.Int("links", len(shortURLs)),
+			logger.ErrorField(err),
+		)
+		return nil
+	}
+
+	return visitors
+}
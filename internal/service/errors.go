@@ -2,6 +2,112 @@ package service
 
 import "errors"
 
-var ErrLinkNotFound = errors.New("link not found")
-var ErrLinkBadRequest = errors.New("uncorrected link or request body")
-var ErrTooManyAttempts = errors.New("too many generation attempts")
+// Kind classifies service errors. Transports map a kind to their own status
+// in one place instead of matching every error.
+type Kind string
+
+const (
+	KindInternal      Kind = "internal"
+	KindValidation    Kind = "validation"
+	KindUnauthorized  Kind = "unauthorized"
+	KindNotFound      Kind = "not_found"
+	KindConflict      Kind = "conflict"
+	KindGone          Kind = "gone"
+	KindUnprocessable Kind = "unprocessable"
+	KindRateLimited   Kind = "rate_limited"
+	KindUnavailable   Kind = "unavailable"
+)
+
+// Error is an expected failure with a kind and a stable machine-readable
+// code. Field names the request parameter at fault, if there is one.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Field   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func NewError(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func newFieldError(field, code, message string) *Error {
+	return &Error{Kind: KindValidation, Code: code, Field: field, Message: message}
+}
+
+// FieldError ties an error to the request parameter that caused it, for
+// errors that may come from several parameters.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+func WithField(field string, err error) error {
+	return &FieldError{Field: field, Err: err}
+}
+
+// ErrorOf returns the first *Error in the chain of err, or nil for an
+// unexpected error.
+func ErrorOf(err error) *Error {
+	var serviceErr *Error
+	if errors.As(err, &serviceErr) {
+		return serviceErr
+	}
+
+	return nil
+}
+
+// KindOf returns the kind of err, KindInternal for unexpected errors.
+func KindOf(err error) Kind {
+	if serviceErr := ErrorOf(err); serviceErr != nil {
+		return serviceErr.Kind
+	}
+
+	return KindInternal
+}
+
+var ErrLinkNotFound = NewError(KindNotFound, "link_not_found", "link not found")
+var ErrLinkBadRequest = NewError(KindValidation, "bad_request", "uncorrected link or request body")
+var ErrURLInvalid = newFieldError("url", "url_invalid", "url must be an absolute URL of at most 1000 characters")
+var ErrTooManyAttempts = NewError(KindUnavailable, "generation_failed", "too many generation attempts")
+var ErrAliasInvalid = newFieldError("alias", "alias_invalid", "alias must be 3-32 characters of letters, digits, '-' or '_'")
+var ErrAliasTaken = NewError(KindConflict, "alias_taken", "alias is already taken")
+var ErrLinkGone = NewError(KindGone, "link_expired", "link has expired")
+var ErrExpirationInvalid = NewError(KindValidation, "expiration_invalid", "expiration must be either a future expires_at or a positive ttl")
+var ErrBatchSize = newFieldError("urls", "batch_size_invalid", "batch must contain between 1 and 1000 urls")
+var ErrIdempotencyKeyInvalid = newFieldError("Idempotency-Key", "idempotency_key_invalid", "Idempotency-Key must not exceed 255 characters")
+var ErrIdempotencyKeyReused = NewError(KindUnprocessable, "idempotency_key_reused", "Idempotency-Key was already used with a different request")
+var ErrLinkClicksExhausted = NewError(KindGone, "link_clicks_exhausted", "link has reached its click limit")
+var ErrMaxClicksInvalid = newFieldError("max_clicks", "max_clicks_invalid", "max_clicks must be a positive number")
+var ErrPasswordInvalid = newFieldError("password", "password_invalid", "password must be between 4 and 72 characters")
+var ErrPasswordRequired = NewError(KindUnauthorized, "password_required", "link is password protected")
+var ErrPasswordIncorrect = NewError(KindUnauthorized, "password_incorrect", "incorrect password")
+var ErrTooManyPasswordAttempts = NewError(KindRateLimited, "too_many_password_attempts", "too many password attempts, try again later")
+var ErrLinkNotActive = NewError(KindNotFound, "link_not_active", "link is not active yet")
+var ErrActivationInvalid = newFieldError("active_from", "activation_invalid", "active_from must be before the link expires")
+var ErrHistoryNotFound = NewError(KindNotFound, "history_not_found", "history entry not found")
+var ErrLinkDisabled = NewError(KindGone, "link_disabled", "link is disabled")
+var ErrStatsIntervalInvalid = newFieldError("interval", "interval_invalid", "interval must be one of hour, day or week")
+var ErrStatsRangeInvalid = NewError(KindValidation, "range_invalid", "from must be before to and the range must not exceed 1000 buckets")
+var ErrTimeInvalid = NewError(KindValidation, "time_invalid", "time must be RFC 3339 or YYYY-MM-DD")
+var ErrTimeZoneInvalid = newFieldError("tz", "time_zone_invalid", "unknown time zone")
+var ErrLimitInvalid = newFieldError("limit", "limit_invalid", "limit must be between 1 and 100")
+var ErrExpandInvalid = newFieldError("expand", "expand_invalid", "expand supports only referrers")
+var ErrFilterInvalid = NewError(KindValidation, "filter_invalid", "created_from must be before created_to and min_clicks must not be negative")
+var ErrTopWindowInvalid = newFieldError("window", "window_invalid", "window must be one of 1h, 24h or 7d")
+var ErrTopUnavailable = NewError(KindUnavailable, "leaderboard_unavailable", "leaderboard is unavailable")
+var ErrSortInvalid = newFieldError("sort", "sort_invalid", "sort must be one of created, clicks or last_access")
+var ErrOrderInvalid = newFieldError("order", "order_invalid", "order must be asc or desc")
+var ErrCursorInvalid = newFieldError("cursor", "cursor_invalid", "cursor is invalid or was issued for a different sort")
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"short_link/internal/logger"
+	"short_link/internal/model"
+	"time"
+)
+
+// ExportLinks passes every short link matching filter to fn as it is read
+// from Postgres. Nothing is buffered, so fn is expected to write the row out
+// right away; an error from fn aborts the export.
+func (s *LinkService) ExportLinks(ctx context.Context, filter model.LinkFilter, fn func(*model.LinkStatsDTO) error) error {
+	if err := validateLinkFilter(&filter); err != nil {
+		s.Logger.Error(err.Error())
+		return err
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+
+	if err != nil {
+		s.Logger.Error("Failed to begin transaction",
+			logger.ErrorField(err))
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.Logger.Error("Failed to rollback transaction",
+					logger.ErrorField(rbErr))
+			}
+		}
+	}()
+
+	if err = s.repo.StreamShortLinks(ctx, tx, filter, fn); err != nil {
+		s.Logger.Error("Error when exporting links " + err.Error())
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.Logger.Error("Failed to commit transaction",
+			logger.ErrorField(err))
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ExportClicks passes the click events of a short link to fn in time order.
+// The window is parsed like the one of GetClickStats. Unknown links are
+// reported before the first event, so the caller can still send an error.
+func (s *LinkService) ExportClicks(ctx context.Context, shortURL string, query model.ClickStatsQuery, fn func(*model.ClickEvent) error) error {
+	from, to, _, err := parseStatsRange(query, time.Now())
+
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return err
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+
+	if err != nil {
+		s.Logger.Error("Failed to begin transaction",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err))
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.Logger.Error("Failed to rollback transaction",
+					logger.String("shortURL", shortURL),
+					logger.ErrorField(rbErr))
+			}
+		}
+	}()
+
+	shortLink, err := s.repo.ExistsShortLink(ctx, tx, shortURL)
+
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return err
+	} else if shortLink == nil || shortLink.IsDeleted() {
+		err = ErrLinkNotFound
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return err
+	}
+
+	if err = s.repo.StreamClickEvents(ctx, tx, shortLink.Id, from, to, fn); err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.Logger.Error("Failed to commit transaction",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err))
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
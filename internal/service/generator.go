@@ -0,0 +1,39 @@
+package service
+
+import (
+	"fmt"
+	"short_link/config"
+)
+
+const (
+	minCodeLength = 4
+	maxCodeLength = 32
+)
+
+// Generator produces short codes. id is the short_links id reserved for the
+// new row; generators that do not derive the code from it may ignore it.
+type Generator interface {
+	Generate(id int64) (string, error)
+}
+
+// NewGenerator builds the generator selected by cfg.Strategy:
+// "base62" (crypto/rand, configurable length), "counter" (obfuscated
+// database id) or "letters" (the legacy math/rand letters-only codes).
+func NewGenerator(cfg config.GeneratorConfig) (Generator, error) {
+	switch cfg.Strategy {
+	case "base62":
+		if cfg.Length < minCodeLength || cfg.Length > maxCodeLength {
+			return nil, fmt.Errorf("short code length must be between %d and %d, got %d", minCodeLength, maxCodeLength, cfg.Length)
+		}
+		return newBase62Generator(cfg.Length), nil
+	case "counter":
+		if cfg.Secret == "" {
+			return nil, fmt.Errorf("counter generator requires SHORT_CODE_SECRET")
+		}
+		return newCounterGenerator(cfg.Secret), nil
+	case "letters":
+		return newLetterGenerator(), nil
+	default:
+		return nil, fmt.Errorf("unknown short code generator %q", cfg.Strategy)
+	}
+}
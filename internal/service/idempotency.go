@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"short_link/internal/model"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// claimIdempotencyKey registers key for the current transaction. When the key
+// was already used for the same request, the stored response is returned and
+// nothing should be created.
+func (s *LinkService) claimIdempotencyKey(ctx context.Context, tx *sql.Tx, key string, linkDTO model.LinkDTO) (*model.LinkStatsDTO, error) {
+	hash, err := requestHash(linkDTO)
+	if err != nil {
+		return nil, err
+	}
+
+	claimed, err := s.repo.ClaimIdempotencyKey(ctx, tx, key, hash)
+	if err != nil {
+		return nil, err
+	} else if claimed {
+		return nil, nil
+	}
+
+	stored, err := s.repo.GetIdempotencyKey(ctx, tx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if stored == nil || stored.RequestHash != hash || stored.Response == nil {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	var statsDTO model.LinkStatsDTO
+
+	if err := json.Unmarshal(stored.Response, &statsDTO); err != nil {
+		return nil, fmt.Errorf("decode stored idempotent response: %w", err)
+	}
+
+	return &statsDTO, nil
+}
+
+func (s *LinkService) saveIdempotencyResponse(ctx context.Context, tx *sql.Tx, key string, statsDTO *model.LinkStatsDTO) error {
+	response, err := json.Marshal(statsDTO)
+	if err != nil {
+		return fmt.Errorf("encode idempotent response: %w", err)
+	}
+
+	return s.repo.SaveIdempotencyResponse(ctx, tx, key, response)
+}
+
+// requestHash fingerprints a create request. The password is left out: the
+// hash is stored in plain sight and a fast digest of it could be
+// brute-forced offline, bypassing bcrypt. A replay that differs only in the
+// password therefore gets the stored response.
+func requestHash(linkDTO model.LinkDTO) (string, error) {
+	linkDTO.Password = ""
+
+	body, err := json.Marshal(linkDTO)
+	if err != nil {
+		return "", fmt.Errorf("encode request: %w", err)
+	}
+
+	sum := sha256.Sum256(body)
+
+	return hex.EncodeToString(sum[:]), nil
+}
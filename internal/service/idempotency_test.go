@@ -0,0 +1,54 @@
+package service
+
+import (
+	"short_link/internal/model"
+	"testing"
+	"time"
+)
+
+func TestRequestHash(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxClicks := 5
+
+	base := model.LinkDTO{URL: "https://example.com", Alias: "promo", ExpiresAt: &expiresAt, MaxClicks: &maxClicks}
+
+	hash := func(dto model.LinkDTO) string {
+		t.Helper()
+
+		h, err := requestHash(dto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(h) != 64 {
+			t.Fatalf("hash %q is not a hex sha256", h)
+		}
+		return h
+	}
+
+	want := hash(base)
+
+	tests := []struct {
+		name   string
+		change func(*model.LinkDTO)
+		same   bool
+	}{
+		{"identical request", func(*model.LinkDTO) {}, true},
+		{"password is left out", func(d *model.LinkDTO) { d.Password = "secret" }, true},
+		{"url", func(d *model.LinkDTO) { d.URL = "https://example.org" }, false},
+		{"alias", func(d *model.LinkDTO) { d.Alias = "other" }, false},
+		{"ttl", func(d *model.LinkDTO) { d.TTL = 60 }, false},
+		{"max clicks", func(d *model.LinkDTO) { n := 6; d.MaxClicks = &n }, false},
+		{"reuse existing", func(d *model.LinkDTO) { d.ReuseExisting = true }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dto := base
+			tt.change(&dto)
+
+			if got := hash(dto); (got == want) != tt.same {
+				t.Errorf("hash equal = %v, want %v", got == want, tt.same)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"short_link/internal/logger"
+	"short_link/internal/model"
+	"short_link/internal/repository/cache"
+	"time"
+)
+
+const linkCacheTTL = 1 * time.Hour
+
+// cacheTTL bounds the default cache lifetime by the link expiration, so a
+// cached entry never outlives the link itself. A zero result means the link
+// must not be cached at all.
+func cacheTTL(expiresAt *time.Time) time.Duration {
+	ttl := linkCacheTTL
+
+	if expiresAt != nil {
+		if left := time.Until(*expiresAt); left < ttl {
+			ttl = left
+		}
+	}
+
+	if ttl <= 0 {
+		return 0
+	}
+
+	return ttl
+}
+
+func (s *LinkService) getCachedLink(ctx context.Context, shortURL string) (string, bool) {
+	if s.cache == nil {
+		return "", false
+	}
+
+	link, err := s.cache.Get(ctx, shortURL)
+
+	if err != nil {
+		if !errors.Is(err, cache.ErrCacheMiss) {
+			s.Logger.Error("Failed to read short link from cache",
+				logger.String("shortURL", shortURL),
+				logger.ErrorField(err),
+			)
+		}
+		return "", false
+	}
+
+	return link, link != ""
+}
+
+// cacheLink stores the redirect target of shortLink. Click-limited and
+// password-protected links are never cached: every access must pass the
+// limit or password check in Postgres. Links that are not active yet are
+// cached only after activation, on their first resolve from Postgres.
+func (s *LinkService) cacheLink(ctx context.Context, shortLink *model.ShortLink, originalURL string) {
+	if s.cache == nil || shortLink.MaxClicks != nil || shortLink.IsProtected() || !shortLink.IsActive(time.Now()) ||
+		shortLink.IsDeleted() || shortLink.Disabled {
+		return
+	}
+
+	ttl := cacheTTL(shortLink.ExpiresAt)
+	if ttl == 0 {
+		return
+	}
+
+	shortURL := shortLink.ShortURL
+
+	err := s.cache.Set(ctx, shortURL, originalURL, ttl)
+
+	if err != nil {
+		s.Logger.Error("Failed to cache short link",
+			logger.String("shortURL", shortURL),
+			logger.String("originalURL", originalURL),
+			logger.ErrorField(err),
+		)
+	} else {
+		s.Logger.Info("Short link cached",
+			logger.String("shortURL", shortURL),
+			logger.String("originalURL", originalURL),
+		)
+	}
+}
+
+func (s *LinkService) cacheLinks(ctx context.Context, links map[string]string) {
+	if s.cache == nil || len(links) == 0 {
+		return
+	}
+
+	if err := s.cache.SetMany(ctx, links, linkCacheTTL); err != nil {
+		s.Logger.Error("Failed to cache short links",
+			logger.Int("count", len(links)),
+			logger.ErrorField(err),
+		)
+	} else {
+		s.Logger.Info("Short links cached", logger.Int("count", len(links)))
+	}
+}
+
+// evictLink drops the cached redirect and info of a short link.
+func (s *LinkService) evictLink(ctx context.Context, shortURL string) {
+	if s.cache == nil {
+		return
+	}
+
+	if err := s.cache.Del(ctx, shortURL); err != nil {
+		s.Logger.Error("Failed to evict short link from cache",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err),
+		)
+	}
+
+	s.evictLinkInfo(ctx, shortURL)
+}
+
+// linkInfoTTL is short because the counters in the info change with every
+// click; changes made through the API evict the entry right away.
+const linkInfoTTL = 30 * time.Second
+
+func (s *LinkService) getCachedLinkInfo(ctx context.Context, shortURL string) (*model.LinkStatsDTO, bool) {
+	if s.cache == nil {
+		return nil, false
+	}
+
+	info, err := s.cache.GetLinkInfo(ctx, shortURL)
+
+	if err != nil {
+		if !errors.Is(err, cache.ErrCacheMiss) {
+			s.Logger.Error("Failed to read link info from cache",
+				logger.String("shortURL", shortURL),
+				logger.ErrorField(err),
+			)
+		}
+		return nil, false
+	}
+
+	return info, true
+}
+
+func (s *LinkService) cacheLinkInfo(ctx context.Context, info *model.LinkStatsDTO) {
+	if s.cache == nil {
+		return
+	}
+
+	ttl := min(cacheTTL(info.ExpiresAt), linkInfoTTL)
+	if ttl <= 0 {
+		return
+	}
+
+	if err := s.cache.SetLinkInfo(ctx, info, ttl); err != nil {
+		s.Logger.Error("Failed to cache link info",
+			logger.String("shortURL", info.ShortURL),
+			logger.ErrorField(err),
+		)
+	}
+}
+
+func (s *LinkService) evictLinkInfo(ctx context.Context, shortURL string) {
+	if s.cache == nil {
+		return
+	}
+
+	if err := s.cache.DelLinkInfo(ctx, shortURL); err != nil {
+		s.Logger.Error("Failed to evict link info from cache",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err),
+		)
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheTTL(t *testing.T) {
+	at := func(d time.Duration) *time.Time {
+		t := time.Now().Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name      string
+		expiresAt *time.Time
+		min, max  time.Duration
+	}{
+		{name: "never expires", expiresAt: nil, min: linkCacheTTL, max: linkCacheTTL},
+		{name: "expires after the cache ttl", expiresAt: at(24 * time.Hour), min: linkCacheTTL, max: linkCacheTTL},
+		{name: "expires before the cache ttl", expiresAt: at(10 * time.Minute), min: 9 * time.Minute, max: 10 * time.Minute},
+		{name: "already expired", expiresAt: at(-time.Minute), min: 0, max: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cacheTTL(tt.expiresAt); got < tt.min || got > tt.max {
+				t.Errorf("cacheTTL() = %v, want between %v and %v", got, tt.min, tt.max)
+			}
+		})
+	}
+}
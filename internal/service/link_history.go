@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"short_link/internal/logger"
+	"short_link/internal/model"
+)
+
+// UpdateDestination points an existing short link at a new url and records
+// the change in its history.
+func (s *LinkService) UpdateDestination(ctx context.Context, shortURL, newURL string) (*model.LinkStatsDTO, error) {
+	if err := ValidLink(newURL); err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL), logger.String("originalURL", newURL))
+		return nil, err
+	}
+
+	return s.changeDestination(ctx, shortURL, func(*sql.Tx, *model.ShortLink) (string, error) {
+		return newURL, nil
+	})
+}
+
+// RollbackDestination restores the url a short link had before the given
+// history entry. The rollback is recorded as a new history entry.
+func (s *LinkService) RollbackDestination(ctx context.Context, shortURL string, historyID int64) (*model.LinkStatsDTO, error) {
+	return s.changeDestination(ctx, shortURL, func(tx *sql.Tx, shortLink *model.ShortLink) (string, error) {
+		entry, err := s.repo.GetLinkHistoryEntry(ctx, tx, shortLink.Id, historyID)
+		if err != nil {
+			return "", err
+		} else if entry == nil {
+			return "", ErrHistoryNotFound
+		}
+
+		return entry.OldURL, nil
+	})
+}
+
+// changeDestination locks the short link, asks target for the new url and,
+// when it differs from the current one, repoints the link and records the
+// change. The cached redirect is refreshed right after the commit.
+func (s *LinkService) changeDestination(ctx context.Context, shortURL string, target func(*sql.Tx, *model.ShortLink) (string, error)) (*model.LinkStatsDTO, error) {
+	tx, err := s.repo.BeginTx(ctx)
+
+	if err != nil {
+		s.Logger.Error("Failed to begin transaction",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err))
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.Logger.Error("Failed to rollback transaction",
+					logger.String("shortURL", shortURL),
+					logger.ErrorField(rbErr))
+			}
+		}
+	}()
+
+	shortLink, oldURL, err := s.repo.LockShortLink(ctx, tx, shortURL)
+
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	} else if shortLink == nil || shortLink.IsDeleted() {
+		err = ErrLinkNotFound
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
+
+	newURL, err := target(tx, shortLink)
+
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
+
+	if newURL != oldURL {
+		var link *model.Link
+
+		link, err = s.repo.ExistsOriginalLink(ctx, tx, newURL)
+
+		if err != nil {
+			s.Logger.Error(err.Error(), logger.String("originalURL", newURL))
+			return nil, err
+		} else if link == nil {
+			link, err = s.repo.CreateOriginalLink(ctx, tx, newURL)
+
+			if err != nil {
+				s.Logger.Error(err.Error(), logger.String("originalURL", newURL))
+				return nil, err
+			}
+		}
+
+		if err = s.repo.UpdateShortLinkURL(ctx, tx, shortLink.Id, link.Id); err != nil {
+			s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+			return nil, err
+		}
+
+		if _, err = s.repo.AddLinkHistory(ctx, tx, shortLink.Id, oldURL, newURL); err != nil {
+			s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+			return nil, err
+		}
+
+		shortLink.IdURL = link.Id
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.Logger.Error("Failed to commit transaction",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err))
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if newURL != oldURL {
+		s.cacheLink(ctx, shortLink, newURL)
+		s.evictLinkInfo(ctx, shortURL)
+
+		s.Logger.Info("Short link destination changed",
+			logger.String("shortURL", shortURL),
+			logger.String("oldURL", oldURL),
+			logger.String("originalURL", newURL))
+	}
+
+	statsDTO := model.LinkStatsDTO{
+		URL:            newURL,
+		ShortURL:       shortLink.ShortURL,
+		CreatedAt:      shortLink.CreatedAt,
+		AccessedAt:     shortLink.AccessedAt,
+		AccessedCount:  shortLink.AccessedCount,
+		UniqueVisitors: s.uniqueVisitors(ctx, shortLink.ShortURL)[shortLink.ShortURL],
+		BotCount:       shortLink.BotCount,
+		ExpiresAt:      shortLink.ExpiresAt,
+		MaxClicks:      shortLink.MaxClicks,
+		Protected:      shortLink.IsProtected(),
+		ActiveFrom:     shortLink.ActiveFrom,
+		Disabled:       shortLink.Disabled,
+	}
+
+	return &statsDTO, nil
+}
+
+func (s *LinkService) GetHistory(ctx context.Context, shortURL string) ([]model.LinkHistoryDTO, error) {
+	tx, err := s.repo.BeginTx(ctx)
+
+	if err != nil {
+		s.Logger.Error("Failed to begin transaction",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err))
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.Logger.Error("Failed to rollback transaction",
+					logger.String("shortURL", shortURL),
+					logger.ErrorField(rbErr))
+			}
+		}
+	}()
+
+	shortLink, err := s.repo.ExistsShortLink(ctx, tx, shortURL)
+
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	} else if shortLink == nil || shortLink.IsDeleted() {
+		err = ErrLinkNotFound
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
+
+	history, err := s.repo.GetLinkHistory(ctx, tx, shortLink.Id)
+
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.Logger.Error("Failed to commit transaction",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err))
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return history, nil
+}
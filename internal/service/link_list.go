@@ -0,0 +1,105 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"short_link/internal/model"
+	"strings"
+)
+
+const defaultPageSize = 20
+
+// parseLinkPage validates the sort, order, limit and cursor of a listing
+// query. A cursor is only accepted with the sort and order it was issued for.
+func parseLinkPage(query model.LinkListQuery) (model.LinkPage, error) {
+	page := model.LinkPage{Sort: query.Sort, Desc: true, Limit: query.Limit}
+
+	if page.Sort == "" {
+		page.Sort = "created"
+	}
+	if page.Sort != "created" && page.Sort != "clicks" && page.Sort != "last_access" {
+		return page, ErrSortInvalid
+	}
+
+	switch query.Order {
+	case "", "desc":
+	case "asc":
+		page.Desc = false
+	default:
+		return page, ErrOrderInvalid
+	}
+
+	if page.Limit == 0 {
+		page.Limit = defaultPageSize
+	}
+	if page.Limit < 1 || page.Limit > maxLimit {
+		return page, ErrLimitInvalid
+	}
+
+	if query.Cursor != "" {
+		cursor, err := decodeLinkCursor(query.Cursor)
+		if err != nil || cursor.Sort != page.Sort || cursor.Desc != page.Desc {
+			return page, ErrCursorInvalid
+		}
+		page.After = cursor
+	}
+
+	return page, nil
+}
+
+// validateLinkFilter checks the filter and brings the destination domain to
+// the form stored in links.host: lower case and without "www.".
+func validateLinkFilter(filter *model.LinkFilter) error {
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) ||
+		filter.MinClicks != nil && *filter.MinClicks < 0 {
+		return ErrFilterInvalid
+	}
+
+	filter.Host = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(filter.Host)), "www.")
+
+	return nil
+}
+
+// linkCursor returns the position of link in the listing of the given page.
+func linkCursor(link model.LinkStatsDTO, page model.LinkPage) *model.LinkCursor {
+	cursor := &model.LinkCursor{Sort: page.Sort, Desc: page.Desc, Id: link.Id}
+
+	switch page.Sort {
+	case "created":
+		cursor.CreatedAt = &link.CreatedAt
+	case "clicks":
+		cursor.Clicks = link.AccessedCount
+	case "last_access":
+		cursor.AccessedAt = link.AccessedAt
+	}
+
+	return cursor
+}
+
+// encodeLinkCursor makes the opaque next_cursor handed out to clients.
+func encodeLinkCursor(cursor *model.LinkCursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeLinkCursor(value string) (*model.LinkCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor model.LinkCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+
+	if cursor.Sort == "created" && cursor.CreatedAt == nil {
+		return nil, ErrCursorInvalid
+	}
+
+	return &cursor, nil
+}
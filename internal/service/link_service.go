@@ -3,56 +3,248 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"short_link/config"
 	"short_link/internal/logger"
+	"short_link/internal/metrics"
 	"short_link/internal/model"
 	"short_link/internal/repository/cache"
 	"short_link/internal/repository/database"
 	"sync"
 	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 type LinkService struct {
-	repo   *database.LinkRepository
-	cache  *cache.RedisClient
-	Logger *logger.Logger
-	mu     sync.Mutex
+	repo         *database.LinkRepository
+	cache        *cache.RedisClient
+	generator    Generator
+	clicks       ClickRecorder
+	analyticsCfg config.AnalyticsConfig
+	attempts     *attemptLimiter
+	Logger       *logger.Logger
+	mu           sync.Mutex
 }
 
-func NewLinkService(repo *database.LinkRepository, cache *cache.RedisClient, logger *logger.Logger) *LinkService {
+func NewLinkService(repo *database.LinkRepository, cache *cache.RedisClient, generator Generator, clicks ClickRecorder,
+	analyticsCfg config.AnalyticsConfig, logger *logger.Logger) *LinkService {
 	return &LinkService{
-		repo:   repo,
-		cache:  cache,
-		Logger: logger,
-		mu:     sync.Mutex{},
+		repo:         repo,
+		cache:        cache,
+		generator:    generator,
+		clicks:       clicks,
+		analyticsCfg: analyticsCfg,
+		attempts:     newAttemptLimiter(maxPasswordAttempts, passwordAttemptWindow),
+		Logger:       logger,
+		mu:           sync.Mutex{},
 	}
 }
 
-func (s *LinkService) generateUniqueShortLink(ctx context.Context, tx *sql.Tx) (string, error) {
+// generateUniqueShortLink reserves a short_links id and generates a code for it
+// that is not taken yet. Every attempt reserves a fresh id, so generators that
+// derive the code from the id also move on after a collision with an alias.
+func (s *LinkService) generateUniqueShortLink(ctx context.Context, tx *sql.Tx) (int64, string, error) {
 	const maxAttempts = 100
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	for i := 0; i < maxAttempts; i++ {
-		shortLink := generationShortLink()
+		ids, err := s.repo.NextShortLinkIDs(ctx, tx, 1)
+		if err != nil {
+			return 0, "", err
+		}
+
+		shortLink, err := s.generator.Generate(ids[0])
+		if err != nil {
+			return 0, "", fmt.Errorf("Error generating the short url: %w", err)
+		}
 
 		v, err := s.repo.ExistsShortLink(ctx, tx, shortLink)
 		if err != nil {
-			return "", fmt.Errorf("Error checking the short url: %w", err)
+			return 0, "", fmt.Errorf("Error checking the short url: %w", err)
 		} else if v == nil {
-			return shortLink, nil
+			return ids[0], shortLink, nil
+		}
+
+		metrics.GenerationRetries.Inc()
+	}
+
+	return 0, "", ErrTooManyAttempts
+}
+
+// generateUniqueShortLinks returns count reserved ids with distinct codes that
+// are not taken yet, checking collisions for the whole set in one query per
+// attempt.
+func (s *LinkService) generateUniqueShortLinks(ctx context.Context, tx *sql.Tx, count int) ([]int64, []string, error) {
+	const maxAttempts = 100
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]int64, 0, count)
+	codes := make([]string, 0, count)
+	seen := make(map[string]struct{}, count)
+
+	for i := 0; i < maxAttempts && len(codes) < count; i++ {
+		reserved, err := s.repo.NextShortLinkIDs(ctx, tx, count-len(codes))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		candidates := make([]string, 0, len(reserved))
+		candidateIDs := make([]int64, 0, len(reserved))
+
+		for _, id := range reserved {
+			shortLink, err := s.generator.Generate(id)
+			if err != nil {
+				return nil, nil, fmt.Errorf("Error generating the short url: %w", err)
+			}
+			if _, ok := seen[shortLink]; ok {
+				metrics.GenerationRetries.Inc()
+				continue
+			}
+			seen[shortLink] = struct{}{}
+			candidates = append(candidates, shortLink)
+			candidateIDs = append(candidateIDs, id)
+		}
+
+		taken, err := s.repo.ExistingShortLinks(ctx, tx, candidates)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error checking the short urls: %w", err)
+		}
+
+		takenSet := make(map[string]struct{}, len(taken))
+		for _, t := range taken {
+			takenSet[t] = struct{}{}
+		}
+
+		metrics.GenerationRetries.Add(float64(len(taken)))
+
+		for j, c := range candidates {
+			if _, ok := takenSet[c]; !ok {
+				codes = append(codes, c)
+				ids = append(ids, candidateIDs[j])
+			}
+		}
+	}
+
+	if len(codes) < count {
+		return nil, nil, ErrTooManyAttempts
+	}
+
+	return ids, codes, nil
+}
+
+// reserveShortLink returns the id and code for a new short link: the
+// requested alias when it is free, or a generated one when no alias was given.
+func (s *LinkService) reserveShortLink(ctx context.Context, tx *sql.Tx, alias string) (int64, string, error) {
+	if alias == "" {
+		return s.generateUniqueShortLink(ctx, tx)
+	}
+
+	v, err := s.repo.ExistsShortLink(ctx, tx, alias)
+	if err != nil {
+		return 0, "", fmt.Errorf("Error checking the alias: %w", err)
+	} else if v != nil {
+		return 0, "", ErrAliasTaken
+	}
+
+	ids, err := s.repo.NextShortLinkIDs(ctx, tx, 1)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return ids[0], alias, nil
+}
+
+// insertShortLink reserves a code for newLink and inserts it. A generated
+// code taken concurrently between the check and the insert is replaced by a
+// fresh one; a taken alias is reported as ErrAliasTaken.
+func (s *LinkService) insertShortLink(ctx context.Context, tx *sql.Tx, alias string, newLink model.ShortLink) (*model.ShortLink, error) {
+	const maxAttempts = 5
+
+	for i := 0; i < maxAttempts; i++ {
+		id, shortURL, err := s.reserveShortLink(ctx, tx, alias)
+		if err != nil {
+			return nil, err
+		}
+
+		newLink.Id, newLink.ShortURL = id, shortURL
+
+		shortLink, err := s.repo.CreateShortLink(ctx, tx, newLink)
+		if !errors.Is(err, database.ErrShortLinkExists) {
+			return shortLink, err
+		} else if alias != "" {
+			return nil, ErrAliasTaken
 		}
+
+		metrics.GenerationRetries.Inc()
 	}
 
-	return "", ErrTooManyAttempts
+	return nil, ErrTooManyAttempts
 }
 
-func (s *LinkService) Create(ctx context.Context, originalURL string) (*model.LinkStatsDTO, error) {
+// Create creates a short link. A non-empty idempotencyKey makes retries of
+// the same request return the originally created link instead of a new one.
+func (s *LinkService) Create(ctx context.Context, linkDTO model.LinkDTO, idempotencyKey string) (*model.LinkStatsDTO, error) {
+	originalURL := linkDTO.URL
+
 	if err := ValidLink(originalURL); err != nil {
 		s.Logger.Error(err.Error(), logger.String("originalURL", originalURL))
 		return nil, err
 	}
 
+	if linkDTO.Alias != "" {
+		if err := ValidAlias(linkDTO.Alias); err != nil {
+			s.Logger.Error(err.Error(), logger.String("alias", linkDTO.Alias))
+			return nil, err
+		}
+	}
+
+	expiresAt, err := ResolveExpiration(linkDTO.ExpiresAt, linkDTO.TTL, time.Now())
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("originalURL", originalURL))
+		return nil, err
+	}
+
+	if err = ValidActivation(linkDTO.ActiveFrom, expiresAt); err != nil {
+		s.Logger.Error(err.Error(), logger.String("originalURL", originalURL))
+		return nil, err
+	}
+
+	if linkDTO.MaxClicks != nil && *linkDTO.MaxClicks < 1 {
+		err = ErrMaxClicksInvalid
+		s.Logger.Error(err.Error(), logger.String("originalURL", originalURL))
+		return nil, err
+	}
+
+	var passwordHash *string
+
+	if linkDTO.Password != "" {
+		if err = ValidPassword(linkDTO.Password); err != nil {
+			s.Logger.Error(err.Error(), logger.String("originalURL", originalURL))
+			return nil, err
+		}
+
+		var hash []byte
+
+		hash, err = bcrypt.GenerateFromPassword([]byte(linkDTO.Password), bcrypt.DefaultCost)
+		if err != nil {
+			s.Logger.Error("Failed to hash link password", logger.ErrorField(err))
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+
+		hashed := string(hash)
+		passwordHash = &hashed
+	}
+
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		err = ErrIdempotencyKeyInvalid
+		s.Logger.Error(err.Error(), logger.String("originalURL", originalURL))
+		return nil, err
+	}
+
 	tx, err := s.repo.BeginTx(ctx)
 
 	if err != nil {
@@ -72,6 +264,29 @@ func (s *LinkService) Create(ctx context.Context, originalURL string) (*model.Li
 		}
 	}()
 
+	if idempotencyKey != "" {
+		var stored *model.LinkStatsDTO
+
+		stored, err = s.claimIdempotencyKey(ctx, tx, idempotencyKey, linkDTO)
+
+		if err != nil {
+			s.Logger.Error(err.Error(), logger.String("idempotencyKey", idempotencyKey))
+			return nil, err
+		} else if stored != nil {
+			if err := tx.Commit(); err != nil {
+				s.Logger.Error("Failed to commit transaction",
+					logger.String("idempotencyKey", idempotencyKey),
+					logger.ErrorField(err))
+				return nil, fmt.Errorf("failed to commit transaction: %w", err)
+			}
+
+			s.Logger.Info("Replayed idempotent request",
+				logger.String("idempotencyKey", idempotencyKey),
+				logger.String("shortURL", stored.ShortURL))
+			return stored, nil
+		}
+	}
+
 	link, err := s.repo.ExistsOriginalLink(ctx, tx, originalURL)
 
 	if err != nil {
@@ -87,18 +302,60 @@ func (s *LinkService) Create(ctx context.Context, originalURL string) (*model.Li
 		}
 	}
 
-	shortURL, err := s.generateUniqueShortLink(ctx, tx)
+	var shortLink *model.ShortLink
 
-	if err != nil {
-		s.Logger.Error(err.Error(), logger.String("originalURL", originalURL), logger.String("shortURL", shortURL))
-		return nil, err
+	if linkDTO.ReuseExisting && linkDTO.Alias == "" && expiresAt == nil && linkDTO.MaxClicks == nil && passwordHash == nil &&
+		linkDTO.ActiveFrom == nil {
+		shortLink, err = s.repo.FindReusableShortLink(ctx, tx, link.Id)
+
+		if err != nil {
+			s.Logger.Error(err.Error(), logger.String("originalURL", originalURL))
+			return nil, err
+		}
 	}
 
-	shortLink, err := s.repo.CreateShortLink(ctx, tx, shortURL, link.Id)
+	if shortLink == nil {
+		shortLink, err = s.insertShortLink(ctx, tx, linkDTO.Alias, model.ShortLink{
+			IdURL:        link.Id,
+			ExpiresAt:    expiresAt,
+			MaxClicks:    linkDTO.MaxClicks,
+			PasswordHash: passwordHash,
+			ActiveFrom:   linkDTO.ActiveFrom,
+		})
 
-	if err != nil {
-		s.Logger.Error(err.Error(), logger.String("originalURL", originalURL), logger.String("shortURL", shortURL))
-		return nil, err
+		if err != nil {
+			s.Logger.Error(err.Error(), logger.String("originalURL", originalURL), logger.String("alias", linkDTO.Alias))
+			return nil, err
+		}
+	} else {
+		s.Logger.Info("Reusing existing short link",
+			logger.String("originalURL", originalURL),
+			logger.String("shortURL", shortLink.ShortURL))
+	}
+
+	shortURL := shortLink.ShortURL
+
+	var statsDTO model.LinkStatsDTO
+
+	statsDTO = model.LinkStatsDTO{
+		URL:           link.URL,
+		ShortURL:      shortLink.ShortURL,
+		CreatedAt:     shortLink.CreatedAt,
+		AccessedAt:    shortLink.AccessedAt,
+		AccessedCount: shortLink.AccessedCount,
+		ExpiresAt:     shortLink.ExpiresAt,
+		MaxClicks:     shortLink.MaxClicks,
+		Protected:     shortLink.IsProtected(),
+		ActiveFrom:    shortLink.ActiveFrom,
+	}
+
+	if idempotencyKey != "" {
+		err = s.saveIdempotencyResponse(ctx, tx, idempotencyKey, &statsDTO)
+
+		if err != nil {
+			s.Logger.Error(err.Error(), logger.String("idempotencyKey", idempotencyKey))
+			return nil, err
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -109,102 +366,179 @@ func (s *LinkService) Create(ctx context.Context, originalURL string) (*model.Li
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	if s.cache != nil {
-		err := s.cache.Set(ctx, shortURL, originalURL, 1*time.Hour)
+	s.cacheLink(ctx, shortLink, originalURL)
+
+	s.Logger.Info("Created Short Link", logger.String("shortURL", shortURL))
+
+	return &statsDTO, nil
+}
+
+func setBatchError(result *model.BatchLinkResultDTO, err error) {
+	result.Error = err.Error()
+	result.Code = string(KindInternal)
+
+	if serviceErr := ErrorOf(err); serviceErr != nil {
+		result.Code, result.Field = serviceErr.Code, serviceErr.Field
+	}
+}
+
+// CreateBatch creates a short link for every url. Invalid urls are reported in
+// their own result item and do not fail the rest of the batch.
+func (s *LinkService) CreateBatch(ctx context.Context, originalURLs []string) ([]model.BatchLinkResultDTO, error) {
+	const maxBatchSize = 1000
+
+	if len(originalURLs) == 0 || len(originalURLs) > maxBatchSize {
+		s.Logger.Error(ErrBatchSize.Error())
+		return nil, ErrBatchSize
+	}
+
+	results := make([]model.BatchLinkResultDTO, len(originalURLs))
+	valid := make([]int, 0, len(originalURLs))
+	validURLs := make([]string, 0, len(originalURLs))
+
+	for i, originalURL := range originalURLs {
+		results[i].URL = originalURL
+
+		if err := ValidLink(originalURL); err != nil {
+			setBatchError(&results[i], err)
+			continue
+		}
 
+		valid = append(valid, i)
+		validURLs = append(validURLs, originalURL)
+	}
+
+	if len(valid) == 0 {
+		s.Logger.Info("Batch contains no valid urls", logger.Int("size", len(originalURLs)))
+		return results, nil
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+
+	if err != nil {
+		s.Logger.Error("Failed to begin transaction",
+			logger.ErrorField(err))
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
 		if err != nil {
-			s.Logger.Error("Failed to cache short link",
-				logger.String("shortURL", shortURL),
-				logger.String("originalURL", originalURL),
-				logger.ErrorField(err),
-			)
-		} else {
-			s.Logger.Info("Short link cached",
-				logger.String("shortURL", shortURL),
-				logger.String("originalURL", originalURL),
-			)
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.Logger.Error("Failed to rollback transaction",
+					logger.ErrorField(rbErr))
+			}
 		}
+	}()
+
+	linkIDs, err := s.repo.CreateOriginalLinks(ctx, tx, validURLs)
+
+	if err != nil {
+		s.Logger.Error(err.Error())
+		return nil, err
 	}
 
-	var linkDTO model.LinkStatsDTO
+	ids, codes, err := s.generateUniqueShortLinks(ctx, tx, len(valid))
 
-	linkDTO = model.LinkStatsDTO{
-		URL:           link.URL,
-		ShortURL:      shortLink.ShortURL,
-		CreatedAt:     shortLink.CreatedAt,
-		AccessedAt:    shortLink.AccessedAt,
-		AccessedCount: shortLink.AccessedCount,
+	if err != nil {
+		s.Logger.Error(err.Error())
+		return nil, err
 	}
 
-	s.Logger.Info("Created Short Link", logger.String("shortURL", shortURL))
+	idURLs := make([]int64, len(valid))
+	for i, originalURL := range validURLs {
+		idURLs[i] = linkIDs[originalURL]
+	}
 
-	return &linkDTO, nil
+	_, err = s.repo.CreateShortLinks(ctx, tx, ids, codes, idURLs)
+
+	if err != nil {
+		s.Logger.Error(err.Error())
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.Logger.Error("Failed to commit transaction",
+			logger.ErrorField(err))
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	cached := make(map[string]string, len(valid))
+
+	for i, idx := range valid {
+		results[idx].ShortURL = codes[i]
+		cached[codes[i]] = validURLs[i]
+	}
+
+	s.cacheLinks(ctx, cached)
+
+	s.Logger.Info("Created Short Links batch",
+		logger.Int("size", len(originalURLs)),
+		logger.Int("created", len(valid)))
+
+	return results, nil
 }
 
-func (s *LinkService) GetOriginalLink(ctx context.Context, shortURL string) (*string, error) {
+// GetOriginalLink resolves a visit to its destination and counts it. Bots are
+// redirected as well, but counted apart from people.
+func (s *LinkService) GetOriginalLink(ctx context.Context, visit Visit) (*string, error) {
+	shortURL := visit.ShortURL
+
 	if len(shortURL) == 0 {
 		s.Logger.Error("Bad request: size shortURL eq 0")
 		return nil, ErrLinkBadRequest
 	}
 
-	link, err := s.cache.Get(ctx, shortURL)
+	// Only links without click limits are cached, so a cache hit is counted
+	// in Redis and never touches Postgres.
+	if link, ok := s.getCachedLink(ctx, shortURL); ok {
+		metrics.CacheRequests.WithLabelValues("hit").Inc()
+		s.countVisit(ctx, visit, true)
+		return &link, nil
+	}
 
-	err = s.cache.Set(ctx, shortURL, link, 1*time.Hour)
+	metrics.CacheRequests.WithLabelValues("miss").Inc()
 
+	link, err := s.resolveLink(ctx, shortURL, nil, visit.IsBot())
 	if err != nil {
-		s.Logger.Error("Failed to cache short link",
-			logger.String("shortURL", shortURL),
-			logger.String("originalURL", link),
-			logger.ErrorField(err),
-		)
-	} else {
-		s.Logger.Info("Short link cached",
-			logger.String("shortURL", shortURL),
-			logger.String("originalURL", link),
-		)
+		return nil, err
 	}
 
-	if err == nil {
-		tx, err := s.repo.BeginTx(ctx)
+	s.countVisit(ctx, visit, false)
 
-		if err != nil {
-			s.Logger.Error("Failed to begin transaction",
-				logger.String("shortURL", shortURL),
-				logger.ErrorField(err))
-			return nil, fmt.Errorf("failed to begin transaction: %w", err)
-		}
+	return link, nil
+}
 
-		defer func() {
-			if err != nil {
-				if rbErr := tx.Rollback(); rbErr != nil {
-					s.Logger.Error("Failed to rollback transaction",
-						logger.String("shortURL", shortURL),
-						logger.ErrorField(rbErr))
-				}
-			}
-		}()
+// UnlockLink resolves a password-protected short link. Attempts are limited
+// per short link and only successful ones are given back, so the password
+// cannot be brute-forced.
+func (s *LinkService) UnlockLink(ctx context.Context, visit Visit, password string) (*string, error) {
+	shortURL := visit.ShortURL
 
-		err = s.repo.AccessedCountIncrement(ctx, tx, shortURL)
-		if err != nil {
-			s.Logger.Error("Error during data update",
-				logger.String("shortURL", shortURL),
-				logger.ErrorField(err))
-			return nil, fmt.Errorf("error updating data: %w", err)
-		} else {
-			s.Logger.Info("successful data update")
-		}
+	if len(shortURL) == 0 {
+		s.Logger.Error("Bad request: size shortURL eq 0")
+		return nil, ErrLinkBadRequest
+	}
 
-		if err := tx.Commit(); err != nil {
-			s.Logger.Error("Failed to commit transaction",
-				logger.String("originalURL", link),
-				logger.String("shortURL", shortURL),
-				logger.ErrorField(err))
-			return nil, fmt.Errorf("failed to commit transaction: %w", err)
-		}
+	if err := s.reservePasswordAttempt(ctx, shortURL); err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
 
-		return &link, nil
+	link, err := s.resolveLink(ctx, shortURL, &password, visit.IsBot())
+	if err != nil {
+		return nil, err
 	}
 
+	s.releasePasswordAttempt(ctx, shortURL)
+	s.countVisit(ctx, visit, false)
+
+	return link, nil
+}
+
+// resolveLink loads the short link from Postgres, checks that it may still be
+// used and counts the access. password is nil for plain redirects, which are
+// refused for protected links.
+func (s *LinkService) resolveLink(ctx context.Context, shortURL string, password *string, bot bool) (*string, error) {
 	tx, err := s.repo.BeginTx(ctx)
 
 	if err != nil {
@@ -230,16 +564,56 @@ func (s *LinkService) GetOriginalLink(ctx context.Context, shortURL string) (*st
 		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
 		return nil, err
 
-	} else if shortLink == nil {
-		s.Logger.Error("Error: shortLink not found", logger.String("shortURL", shortURL))
-		return nil, fmt.Errorf("Error: shortLink not found")
+	} else if shortLink == nil || shortLink.IsDeleted() {
+		err = ErrLinkNotFound
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	} else if shortLink.Disabled {
+		err = ErrLinkDisabled
+		s.Logger.Info(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	} else if shortLink.IsExpired(time.Now()) {
+		err = ErrLinkGone
+		s.Logger.Info("short link has expired", logger.String("shortURL", shortURL))
+		return nil, err
+	} else if shortLink.IsExhausted() {
+		err = ErrLinkClicksExhausted
+		s.Logger.Info("short link has no clicks left", logger.String("shortURL", shortURL))
+		return nil, err
+	} else if !shortLink.IsActive(time.Now()) {
+		err = ErrLinkNotActive
+		s.Logger.Info(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
+
+	if shortLink.IsProtected() {
+		if password == nil {
+			err = ErrPasswordRequired
+			s.Logger.Info(err.Error(), logger.String("shortURL", shortURL))
+			return nil, err
+		}
+
+		if bcrypt.CompareHashAndPassword([]byte(*shortLink.PasswordHash), []byte(*password)) != nil {
+			err = ErrPasswordIncorrect
+			s.Logger.Info(err.Error(), logger.String("shortURL", shortURL))
+			return nil, err
+		}
 	}
 
-	link, err = s.repo.GetOriginalLink(ctx, tx, shortURL)
+	link, err := s.repo.GetOriginalLink(ctx, tx, shortURL, bot)
 
 	if err != nil {
 		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
 		return nil, err
+	} else if link == "" {
+		// The link changed between the check above and the update: it was
+		// disabled, deleted, used up or expired concurrently.
+		err = ErrLinkGone
+		if shortLink.MaxClicks != nil {
+			err = ErrLinkClicksExhausted
+		}
+		s.Logger.Info(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -250,12 +624,51 @@ func (s *LinkService) GetOriginalLink(ctx context.Context, shortURL string) (*st
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	s.cacheLink(ctx, shortLink, link)
+
 	s.Logger.Info("the original link was obtained from a short", logger.String("shortURL", shortURL), logger.String("originalURL", link))
 
 	return &link, nil
 }
 
-func (s *LinkService) GetAllShortLink(ctx context.Context) ([]model.LinkStatsDTO, error) {
+// GetAllShortLinks returns every short link, newest first, as the listing did
+// before it was paginated. The links are read page by page.
+func (s *LinkService) GetAllShortLinks(ctx context.Context, expandReferrers bool) ([]model.LinkStatsDTO, error) {
+	links := []model.LinkStatsDTO{}
+	query := model.LinkListQuery{Limit: maxLimit}
+
+	for {
+		page, err := s.GetAllShortLink(ctx, query, expandReferrers)
+		if err != nil {
+			return nil, err
+		}
+
+		links = append(links, page.Items...)
+
+		if page.NextCursor == "" {
+			return links, nil
+		}
+
+		query.Cursor = page.NextCursor
+	}
+}
+
+// GetAllShortLink returns one page of the short links matching the query,
+// ordered by its sort with the id as tie-breaker. The next cursor is set when
+// more links follow. With expandReferrers every link also carries its
+// all-time top referrer domains.
+func (s *LinkService) GetAllShortLink(ctx context.Context, query model.LinkListQuery, expandReferrers bool) (*model.LinkListDTO, error) {
+	page, err := parseLinkPage(query)
+
+	if err == nil {
+		err = validateLinkFilter(&query.Filter)
+	}
+
+	if err != nil {
+		s.Logger.Error(err.Error())
+		return nil, err
+	}
+
 	tx, err := s.repo.BeginTx(ctx)
 
 	if err != nil {
@@ -273,7 +686,7 @@ func (s *LinkService) GetAllShortLink(ctx context.Context) ([]model.LinkStatsDTO
 		}
 	}()
 
-	links, err := s.repo.GetAllShortLink(ctx, tx)
+	links, err := s.repo.ListShortLinks(ctx, tx, query.Filter, page)
 
 	if err != nil {
 		s.Logger.Error("Error when receiving all records " + err.Error())
@@ -281,6 +694,56 @@ func (s *LinkService) GetAllShortLink(ctx context.Context) ([]model.LinkStatsDTO
 		return nil, err
 	}
 
+	list := &model.LinkListDTO{Items: links}
+
+	if len(links) > page.Limit {
+		list.Items = links[:page.Limit]
+
+		list.NextCursor, err = encodeLinkCursor(linkCursor(list.Items[page.Limit-1], page))
+
+		if err != nil {
+			s.Logger.Error("Error when encoding cursor " + err.Error())
+
+			return nil, err
+		}
+	}
+
+	links = list.Items
+
+	if expandReferrers && len(links) > 0 {
+		shortURLs := make([]string, len(links))
+		for i, link := range links {
+			shortURLs[i] = link.ShortURL
+		}
+
+		var referrers map[string][]model.ClickShareDTO
+
+		referrers, err = s.repo.GetTopReferrersByShortURLs(ctx, tx, shortURLs, expandedReferrers)
+
+		if err != nil {
+			s.Logger.Error("Error when receiving referrers " + err.Error())
+
+			return nil, err
+		}
+
+		for i := range links {
+			links[i].Referrers = referrers[links[i].ShortURL]
+		}
+	}
+
+	if len(links) > 0 {
+		shortURLs := make([]string, len(links))
+		for i, link := range links {
+			shortURLs[i] = link.ShortURL
+		}
+
+		visitors := s.uniqueVisitors(ctx, shortURLs...)
+
+		for i := range links {
+			links[i].UniqueVisitors = visitors[links[i].ShortURL]
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		s.Logger.Error("Failed to commit transaction",
 			logger.ErrorField(err))
@@ -289,5 +752,61 @@ func (s *LinkService) GetAllShortLink(ctx context.Context) ([]model.LinkStatsDTO
 
 	s.Logger.Info("All records have been received")
 
-	return links, nil
+	return list, nil
+}
+
+// GetLinkInfo returns the stats of one short link without counting a click.
+// The result is served from the cache for up to linkInfoTTL, so its counters
+// may lag behind a little.
+func (s *LinkService) GetLinkInfo(ctx context.Context, shortURL string) (*model.LinkStatsDTO, error) {
+	if info, ok := s.getCachedLinkInfo(ctx, shortURL); ok {
+		return info, nil
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+
+	if err != nil {
+		s.Logger.Error("Failed to begin transaction",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err))
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.Logger.Error("Failed to rollback transaction",
+					logger.String("shortURL", shortURL),
+					logger.ErrorField(rbErr))
+			}
+		}
+	}()
+
+	links, err := s.repo.GetLinkStatsByShortURLs(ctx, tx, []string{shortURL})
+
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
+
+	info, ok := links[shortURL]
+
+	if !ok {
+		err = ErrLinkNotFound
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.Logger.Error("Failed to commit transaction",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err))
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	info.UniqueVisitors = s.uniqueVisitors(ctx, shortURL)[shortURL]
+
+	s.cacheLinkInfo(ctx, &info)
+
+	return &info, nil
 }
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"errors"
+	"short_link/internal/model"
+	"testing"
+)
+
+func TestSetBatchError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCode  string
+		wantField string
+	}{
+		{"invalid url", ValidLink("not a url"), "url_invalid", "url"},
+		{"empty url", ValidLink(""), "url_invalid", "url"},
+		{"service error without field", ErrTooManyAttempts, "generation_failed", ""},
+		{"unexpected error", errors.New("boom"), string(KindInternal), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result model.BatchLinkResultDTO
+			setBatchError(&result, tt.err)
+
+			if result.Code != tt.wantCode || result.Field != tt.wantField || result.Error == "" {
+				t.Errorf("got code %q, field %q, error %q; want code %q, field %q",
+					result.Code, result.Field, result.Error, tt.wantCode, tt.wantField)
+			}
+		})
+	}
+}
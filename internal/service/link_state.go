@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"short_link/internal/logger"
+	"short_link/internal/model"
+)
+
+// DeleteLink soft-deletes a short link. It stops redirecting at once and can
+// be brought back with RestoreLink until the cleaner purges it.
+func (s *LinkService) DeleteLink(ctx context.Context, shortURL string) error {
+	return s.changeLinkState(ctx, shortURL, false, func(tx *sql.Tx, shortLink *model.ShortLink) error {
+		return s.repo.SetShortLinkDeleted(ctx, tx, shortLink.Id, true)
+	})
+}
+
+func (s *LinkService) RestoreLink(ctx context.Context, shortURL string) error {
+	return s.changeLinkState(ctx, shortURL, true, func(tx *sql.Tx, shortLink *model.ShortLink) error {
+		if !shortLink.IsDeleted() {
+			return nil
+		}
+		return s.repo.SetShortLinkDeleted(ctx, tx, shortLink.Id, false)
+	})
+}
+
+// SetLinkDisabled turns redirects of a short link off or back on.
+func (s *LinkService) SetLinkDisabled(ctx context.Context, shortURL string, disabled bool) error {
+	return s.changeLinkState(ctx, shortURL, false, func(tx *sql.Tx, shortLink *model.ShortLink) error {
+		return s.repo.SetShortLinkDisabled(ctx, tx, shortLink.Id, disabled)
+	})
+}
+
+// changeLinkState locks the short link, applies change and drops the cached
+// redirect, so the new state takes effect immediately. Deleted links are
+// reported as not found unless includeDeleted is set.
+func (s *LinkService) changeLinkState(ctx context.Context, shortURL string, includeDeleted bool, change func(*sql.Tx, *model.ShortLink) error) error {
+	tx, err := s.repo.BeginTx(ctx)
+
+	if err != nil {
+		s.Logger.Error("Failed to begin transaction",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err))
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.Logger.Error("Failed to rollback transaction",
+					logger.String("shortURL", shortURL),
+					logger.ErrorField(rbErr))
+			}
+		}
+	}()
+
+	shortLink, _, err := s.repo.LockShortLink(ctx, tx, shortURL)
+
+	if err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return err
+	} else if shortLink == nil || (shortLink.IsDeleted() && !includeDeleted) {
+		err = ErrLinkNotFound
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return err
+	}
+
+	if err = change(tx, shortLink); err != nil {
+		s.Logger.Error(err.Error(), logger.String("shortURL", shortURL))
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.Logger.Error("Failed to commit transaction",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err))
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.evictLink(ctx, shortURL)
+
+	s.Logger.Info("Short link state changed", logger.String("shortURL", shortURL))
+
+	return nil
+}
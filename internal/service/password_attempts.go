@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"short_link/internal/logger"
+	"sync"
+	"time"
+)
+
+const (
+	maxPasswordAttempts   = 5
+	passwordAttemptWindow = 15 * time.Minute
+)
+
+func passwordAttemptsKey(shortURL string) string {
+	return "password_attempts:" + shortURL
+}
+
+// reservePasswordAttempt takes one of the maxPasswordAttempts attempts of the
+// current window before the password is compared, so parallel guesses cannot
+// all pass a check that none of them has counted yet. Without Redis the
+// attempts are limited per instance.
+func (s *LinkService) reservePasswordAttempt(ctx context.Context, shortURL string) error {
+	if s.cache == nil {
+		if !s.attempts.reserve(shortURL, time.Now()) {
+			return ErrTooManyPasswordAttempts
+		}
+		return nil
+	}
+
+	attempts, err := s.cache.IncrWithExpire(ctx, passwordAttemptsKey(shortURL), passwordAttemptWindow)
+	if err != nil {
+		return fmt.Errorf("failed to count password attempt: %w", err)
+	}
+
+	if attempts > maxPasswordAttempts {
+		return ErrTooManyPasswordAttempts
+	}
+
+	return nil
+}
+
+// releasePasswordAttempt gives back the attempt of a successful unlock, so
+// only failures use up the window. The failures themselves stay counted until
+// the window expires.
+func (s *LinkService) releasePasswordAttempt(ctx context.Context, shortURL string) {
+	if s.cache == nil {
+		s.attempts.release(shortURL)
+		return
+	}
+
+	if err := s.cache.DecrIfExists(ctx, passwordAttemptsKey(shortURL)); err != nil {
+		s.Logger.Error("Failed to release password attempt",
+			logger.String("shortURL", shortURL),
+			logger.ErrorField(err),
+		)
+	}
+}
+
+// attemptLimiter is the in-process fixed-window counter used when Redis is
+// not configured.
+type attemptLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	entries map[string]*attemptWindow
+}
+
+type attemptWindow struct {
+	count   int
+	expires time.Time
+}
+
+// attemptLimiterSweepSize is the number of tracked keys above which expired
+// windows are dropped, so the map does not grow with every link ever tried.
+const attemptLimiterSweepSize = 10000
+
+func newAttemptLimiter(limit int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		limit:   limit,
+		window:  window,
+		entries: make(map[string]*attemptWindow),
+	}
+}
+
+func (l *attemptLimiter) reserve(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.entries) > attemptLimiterSweepSize {
+		for k, entry := range l.entries {
+			if !now.Before(entry.expires) {
+				delete(l.entries, k)
+			}
+		}
+	}
+
+	entry, ok := l.entries[key]
+	if !ok || !now.Before(entry.expires) {
+		entry = &attemptWindow{expires: now.Add(l.window)}
+		l.entries[key] = entry
+	}
+
+	entry.count++
+
+	return entry.count <= l.limit
+}
+
+func (l *attemptLimiter) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if entry, ok := l.entries[key]; ok && entry.count > 0 {
+		entry.count--
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAttemptLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newAttemptLimiter(3, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if !limiter.reserve("abc", now) {
+			t.Fatalf("attempt %d refused within the limit", i+1)
+		}
+	}
+
+	if limiter.reserve("abc", now) {
+		t.Fatal("attempt above the limit was allowed")
+	}
+
+	if !limiter.reserve("other", now) {
+		t.Fatal("limit of one key applied to another")
+	}
+
+	if !limiter.reserve("abc", now.Add(time.Minute)) {
+		t.Fatal("attempt refused after the window expired")
+	}
+}
+
+func TestAttemptLimiterRelease(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newAttemptLimiter(2, time.Minute)
+
+	// Successful attempts are given back and never use up the window.
+	for i := 0; i < 10; i++ {
+		if !limiter.reserve("abc", now) {
+			t.Fatalf("released attempt %d counted against the limit", i+1)
+		}
+		limiter.release("abc")
+	}
+
+	limiter.reserve("abc", now)
+	limiter.reserve("abc", now)
+
+	if limiter.reserve("abc", now) {
+		t.Fatal("attempt above the limit was allowed")
+	}
+
+	// Releasing an unknown key must not panic or create an entry.
+	limiter.release("missing")
+	if _, ok := limiter.entries["missing"]; ok {
+		t.Fatal("release created an entry")
+	}
+}
@@ -1,10 +1,26 @@
 package service
 
 import (
-	"math/rand"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	mathrand "math/rand"
 )
 
-func generationShortLink() string {
+const base62Charset = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+var ErrGeneratorExhausted = errors.New("short code space is exhausted")
+
+// letterGenerator is the original generator: six random latin letters.
+type letterGenerator struct{}
+
+func newLetterGenerator() *letterGenerator {
+	return &letterGenerator{}
+}
+
+func (g *letterGenerator) Generate(int64) (string, error) {
 	var (
 		charset string = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 		length  int    = 6
@@ -13,8 +29,159 @@ func generationShortLink() string {
 	sliceByte := make([]byte, length)
 
 	for i := 0; i < length; i++ {
-		sliceByte[i] = charset[rand.Intn(len(charset))]
+		sliceByte[i] = charset[mathrand.Intn(len(charset))]
+	}
+
+	return string(sliceByte), nil
+}
+
+// base62Generator draws codes from crypto/rand, so they cannot be predicted
+// from previously issued ones.
+type base62Generator struct {
+	length int
+}
+
+func newBase62Generator(length int) *base62Generator {
+	return &base62Generator{length: length}
+}
+
+func (g *base62Generator) Generate(int64) (string, error) {
+	// 248 is the largest multiple of 62 that fits in a byte; rejecting bytes
+	// above it keeps every character equally likely.
+	const limit = 248
+
+	code := make([]byte, 0, g.length)
+	buf := make([]byte, g.length*2)
+
+	for len(code) < g.length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("read random bytes: %w", err)
+		}
+
+		for _, b := range buf {
+			if b >= limit {
+				continue
+			}
+			code = append(code, base62Charset[int(b)%len(base62Charset)])
+			if len(code) == g.length {
+				break
+			}
+		}
+	}
+
+	return string(code), nil
+}
+
+// counterGenerator encodes the database id through a keyed Feistel network,
+// which is a bijection on 34-bit values. Consecutive ids therefore produce
+// unrelated codes, while every code can still be decoded back into its id.
+type counterGenerator struct {
+	keys [counterRounds]uint32
+}
+
+const (
+	counterRounds   = 4
+	counterHalfBits = 17
+	counterHalfMask = 1<<counterHalfBits - 1
+	counterMaxID    = 1<<(2*counterHalfBits) - 1
+	// 62^6 > 2^34, so six characters cover the whole id space.
+	counterCodeLength = 6
+)
+
+func newCounterGenerator(secret string) *counterGenerator {
+	sum := sha256.Sum256([]byte(secret))
+
+	var g counterGenerator
+	for i := range g.keys {
+		g.keys[i] = binary.BigEndian.Uint32(sum[i*4:])
+	}
+
+	return &g
+}
+
+func (g *counterGenerator) Generate(id int64) (string, error) {
+	if id < 0 || id > counterMaxID {
+		return "", ErrGeneratorExhausted
+	}
+
+	return encodeBase62(g.permute(uint64(id)), counterCodeLength), nil
+}
+
+// Decode returns the id a code was generated from.
+func (g *counterGenerator) Decode(code string) (int64, error) {
+	v, err := decodeBase62(code)
+	if err != nil {
+		return 0, err
+	}
+
+	if v > counterMaxID {
+		return 0, fmt.Errorf("code %q is out of range", code)
+	}
+
+	return int64(g.unpermute(v)), nil
+}
+
+func (g *counterGenerator) permute(v uint64) uint64 {
+	l, r := uint32(v>>counterHalfBits), uint32(v&counterHalfMask)
+
+	for _, k := range g.keys {
+		l, r = r, l^feistelRound(r, k)
+	}
+
+	return uint64(l)<<counterHalfBits | uint64(r)
+}
+
+func (g *counterGenerator) unpermute(v uint64) uint64 {
+	l, r := uint32(v>>counterHalfBits), uint32(v&counterHalfMask)
+
+	for i := len(g.keys) - 1; i >= 0; i-- {
+		l, r = r^feistelRound(l, g.keys[i]), l
+	}
+
+	return uint64(l)<<counterHalfBits | uint64(r)
+}
+
+func feistelRound(half, key uint32) uint32 {
+	v := half ^ key
+	v *= 0x9e3779b1
+	v ^= v >> 15
+	v *= 0x85ebca77
+	v ^= v >> 13
+
+	return v & counterHalfMask
+}
+
+func encodeBase62(v uint64, width int) string {
+	code := make([]byte, width)
+
+	for i := width - 1; i >= 0; i-- {
+		code[i] = base62Charset[v%62]
+		v /= 62
+	}
+
+	return string(code)
+}
+
+func decodeBase62(code string) (uint64, error) {
+	var v uint64
+
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+
+		var d uint64
+		switch {
+		case c >= '0' && c <= '9':
+			d = uint64(c - '0')
+		case c >= 'a' && c <= 'z':
+			d = uint64(c-'a') + 10
+		case c >= 'A' && c <= 'Z':
+			d = uint64(c-'A') + 36
+		default:
+			return 0, fmt.Errorf("invalid base62 character %q", c)
+		}
+
+		v = v*62 + d
 	}
 
-	return string(sliceByte)
+	return v, nil
 }
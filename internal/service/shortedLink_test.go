@@ -0,0 +1,124 @@
+package service
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestCounterGeneratorRoundTrip(t *testing.T) {
+	g := newCounterGenerator("secret")
+
+	ids := []uint64{0, 1, 2, counterHalfMask, counterHalfMask + 1, counterMaxID - 1, counterMaxID}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100000; i++ {
+		ids = append(ids, uint64(rng.Int63n(counterMaxID+1)))
+	}
+
+	for _, id := range ids {
+		permuted := g.permute(id)
+		if permuted > counterMaxID {
+			t.Fatalf("permute(%d) = %d leaves the 34-bit domain", id, permuted)
+		}
+
+		if got := g.unpermute(permuted); got != id {
+			t.Fatalf("unpermute(permute(%d)) = %d", id, got)
+		}
+
+		code, err := g.Generate(int64(id))
+		if err != nil {
+			t.Fatalf("Generate(%d): %v", id, err)
+		}
+
+		if len(code) != counterCodeLength {
+			t.Fatalf("Generate(%d) = %q, want %d characters", id, code, counterCodeLength)
+		}
+
+		decoded, err := g.Decode(code)
+		if err != nil {
+			t.Fatalf("Decode(%q): %v", code, err)
+		}
+
+		if decoded != int64(id) {
+			t.Fatalf("Decode(Generate(%d)) = %d", id, decoded)
+		}
+	}
+}
+
+func TestCounterGeneratorSecret(t *testing.T) {
+	a, _ := newCounterGenerator("one").Generate(42)
+	b, _ := newCounterGenerator("two").Generate(42)
+
+	if a == b {
+		t.Errorf("different secrets produced the same code %q", a)
+	}
+
+	next, _ := newCounterGenerator("one").Generate(43)
+	if a == next {
+		t.Errorf("consecutive ids produced the same code %q", a)
+	}
+}
+
+func TestCounterGeneratorRange(t *testing.T) {
+	g := newCounterGenerator("secret")
+
+	for _, id := range []int64{-1, counterMaxID + 1} {
+		if _, err := g.Generate(id); !errors.Is(err, ErrGeneratorExhausted) {
+			t.Errorf("Generate(%d) = %v, want ErrGeneratorExhausted", id, err)
+		}
+	}
+
+	for _, code := range []string{"ZZZZZZ", "abc-de", "abc de"} {
+		if _, err := g.Decode(code); err == nil {
+			t.Errorf("Decode(%q) succeeded", code)
+		}
+	}
+}
+
+func TestBase62Generator(t *testing.T) {
+	for _, length := range []int{minCodeLength, 6, maxCodeLength} {
+		g := newBase62Generator(length)
+		seen := make(map[string]struct{})
+
+		for i := 0; i < 1000; i++ {
+			code, err := g.Generate(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(code) != length {
+				t.Fatalf("code %q has %d characters, want %d", code, len(code), length)
+			}
+
+			for _, c := range code {
+				if !strings.ContainsRune(base62Charset, c) {
+					t.Fatalf("code %q contains %q", code, c)
+				}
+			}
+
+			seen[code] = struct{}{}
+		}
+
+		// 1000 draws from at least 62^4 codes repeat only by rare chance.
+		if len(seen) < 990 {
+			t.Errorf("length %d: only %d distinct codes out of 1000", length, len(seen))
+		}
+	}
+}
+
+func TestBase62RoundTrip(t *testing.T) {
+	for _, v := range []uint64{0, 1, 61, 62, 3843, 3844, counterMaxID} {
+		code := encodeBase62(v, counterCodeLength)
+
+		got, err := decodeBase62(code)
+		if err != nil {
+			t.Fatalf("decodeBase62(%q): %v", code, err)
+		}
+
+		if got != v {
+			t.Errorf("decodeBase62(encodeBase62(%d)) = %d", v, got)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"short_link/internal/logger"
+	"short_link/internal/model"
+	"time"
+)
+
+const defaultTopLimit = 20
+
+// topWindows maps the supported leaderboard windows to their length in hours.
+var topWindows = map[string]int{
+	"1h":  1,
+	"24h": 24,
+	"7d":  7 * 24,
+}
+
+// GetTopLinks returns the short links with most human clicks over the last
+// hour, day or week, counted back from now. Clicks are kept per hour, so
+// those in the oldest hour of a window are estimated. Deleted links are left
+// out.
+func (s *LinkService) GetTopLinks(ctx context.Context, window string, limit int) (*model.TopLinksDTO, error) {
+	if window == "" {
+		window = "24h"
+	}
+
+	hours, ok := topWindows[window]
+	if !ok {
+		err := ErrTopWindowInvalid
+		s.Logger.Error(err.Error())
+		return nil, err
+	}
+
+	if limit == 0 {
+		limit = defaultTopLimit
+	}
+
+	if limit < 1 || limit > maxLimit {
+		err := ErrLimitInvalid
+		s.Logger.Error(err.Error())
+		return nil, err
+	}
+
+	if s.cache == nil {
+		s.Logger.Error(ErrTopUnavailable.Error())
+		return nil, ErrTopUnavailable
+	}
+
+	// Deleted links still have clicks in Redis, so a few more are read than
+	// needed to fill the page after they are dropped.
+	ranked, err := s.cache.TopLinks(ctx, hours, limit*2, time.Now())
+
+	if err != nil {
+		s.Logger.Error("Failed to read leaderboard", logger.ErrorField(err))
+		return nil, fmt.Errorf("%w: %w", ErrTopUnavailable, err)
+	}
+
+	top := model.TopLinksDTO{Window: window, Links: []model.TopLinkDTO{}}
+
+	if len(ranked) == 0 {
+		return &top, nil
+	}
+
+	shortURLs := make([]string, len(ranked))
+	for i, r := range ranked {
+		shortURLs[i] = r.Name
+	}
+
+	tx, err := s.repo.BeginTx(ctx)
+
+	if err != nil {
+		s.Logger.Error("Failed to begin transaction",
+			logger.ErrorField(err))
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				s.Logger.Error("Failed to rollback transaction",
+					logger.ErrorField(rbErr))
+			}
+		}
+	}()
+
+	links, err := s.repo.GetLinkStatsByShortURLs(ctx, tx, shortURLs)
+
+	if err != nil {
+		s.Logger.Error(err.Error())
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.Logger.Error("Failed to commit transaction",
+			logger.ErrorField(err))
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, r := range ranked {
+		link, ok := links[r.Name]
+		if !ok {
+			continue
+		}
+
+		top.Links = append(top.Links, model.TopLinkDTO{
+			Rank:         len(top.Links) + 1,
+			Clicks:       r.Clicks,
+			LinkStatsDTO: link,
+		})
+
+		if len(top.Links) == limit {
+			break
+		}
+	}
+
+	return &top, nil
+}
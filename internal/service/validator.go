@@ -3,17 +3,93 @@ package service
 import (
 	"fmt"
 	"net/url"
+	"regexp"
+	"time"
 )
 
+const (
+	minAliasLength = 3
+	maxAliasLength = 32
+	maxLinkTTL     = 10 * 365 * 24 * time.Hour
+
+	minPasswordLength = 4
+	// bcrypt ignores everything after the first 72 bytes.
+	maxPasswordLength = 72
+)
+
+var aliasPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// reservedAliases collide with fixed routes under /oneLink.
+var reservedAliases = map[string]struct{}{
+	"batch":  {},
+	"export": {},
+	"top":    {},
+}
+
 func ValidLink(originalURL string) error {
 
 	if len(originalURL) > 1000 || originalURL == "" {
-		return ErrLinkBadRequest
+		return ErrURLInvalid
 	}
 	_, err := url.ParseRequestURI(originalURL)
 
 	if err != nil {
-		return fmt.Errorf("Error checking the url: %w", err)
+		return fmt.Errorf("%w: %w", ErrURLInvalid, err)
+	}
+
+	return nil
+}
+
+func ValidAlias(alias string) error {
+	if len(alias) < minAliasLength || len(alias) > maxAliasLength {
+		return ErrAliasInvalid
+	}
+
+	if !aliasPattern.MatchString(alias) {
+		return ErrAliasInvalid
+	}
+
+	if _, ok := reservedAliases[alias]; ok {
+		return ErrAliasTaken
+	}
+
+	return nil
+}
+
+func ValidPassword(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return ErrPasswordInvalid
+	}
+
+	return nil
+}
+
+// ResolveExpiration turns the optional expires_at/ttl pair of a request into
+// an absolute expiration time. A nil result means the link never expires.
+func ResolveExpiration(expiresAt *time.Time, ttl int64, now time.Time) (*time.Time, error) {
+	if expiresAt != nil && ttl != 0 {
+		return nil, ErrExpirationInvalid
+	}
+
+	if ttl != 0 {
+		if ttl < 0 || ttl > int64(maxLinkTTL/time.Second) {
+			return nil, ErrExpirationInvalid
+		}
+
+		t := now.Add(time.Duration(ttl) * time.Second)
+		return &t, nil
+	}
+
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, ErrExpirationInvalid
+	}
+
+	return expiresAt, nil
+}
+
+func ValidActivation(activeFrom, expiresAt *time.Time) error {
+	if activeFrom != nil && expiresAt != nil && !activeFrom.Before(*expiresAt) {
+		return ErrActivationInvalid
 	}
 
 	return nil
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidAlias(t *testing.T) {
+	tests := []struct {
+		alias string
+		want  error
+	}{
+		{alias: "abc", want: nil},
+		{alias: "my-link_2024", want: nil},
+		{alias: strings.Repeat("a", maxAliasLength), want: nil},
+		{alias: "ab", want: ErrAliasInvalid},
+		{alias: strings.Repeat("a", maxAliasLength+1), want: ErrAliasInvalid},
+		{alias: "", want: ErrAliasInvalid},
+		{alias: "with space", want: ErrAliasInvalid},
+		{alias: "slash/path", want: ErrAliasInvalid},
+		{alias: "кириллица", want: ErrAliasInvalid},
+		// Internal Redis keys contain ':', an alias must never produce one.
+		{alias: "click:dirty", want: ErrAliasInvalid},
+		{alias: "info:abc", want: ErrAliasInvalid},
+		{alias: "batch", want: ErrAliasTaken},
+		{alias: "export", want: ErrAliasTaken},
+		{alias: "top", want: ErrAliasTaken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			if err := ValidAlias(tt.alias); !errors.Is(err, tt.want) {
+				t.Errorf("ValidAlias(%q) = %v, want %v", tt.alias, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveExpiration(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		expiresAt *time.Time
+		ttl       int64
+		want      *time.Time
+		wantErr   bool
+	}{
+		{name: "never expires"},
+		{name: "ttl", ttl: 3600, want: &future},
+		{name: "expires_at", expiresAt: &future, want: &future},
+		{name: "both", expiresAt: &future, ttl: 60, wantErr: true},
+		{name: "negative ttl", ttl: -1, wantErr: true},
+		{name: "ttl above the limit", ttl: int64(maxLinkTTL/time.Second) + 1, wantErr: true},
+		{name: "expires_at in the past", expiresAt: &past, wantErr: true},
+		{name: "expires_at now", expiresAt: &now, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveExpiration(tt.expiresAt, tt.ttl, now)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrExpirationInvalid) {
+					t.Errorf("error = %v, want ErrExpirationInvalid", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if (got == nil) != (tt.want == nil) || got != nil && !got.Equal(*tt.want) {
+				t.Errorf("ResolveExpiration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
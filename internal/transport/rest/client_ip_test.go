@@ -0,0 +1,55 @@
+package rest
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	h := &HTTPHandler{trustedProxies: []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("127.0.0.1/32"),
+	}}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "untrusted peer cannot spoof", remoteAddr: "203.0.113.7:5000", forwardedFor: []string{"1.2.3.4"}, want: "203.0.113.7"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.2:5000", forwardedFor: []string{"198.51.100.9"}, want: "198.51.100.9"},
+		{
+			name:         "prepended entries are ignored",
+			remoteAddr:   "10.0.0.2:5000",
+			forwardedFor: []string{"1.2.3.4, 198.51.100.9"},
+			want:         "198.51.100.9",
+		},
+		{
+			name:         "chain of trusted proxies",
+			remoteAddr:   "127.0.0.1:5000",
+			forwardedFor: []string{"198.51.100.9, 10.1.2.3", "10.0.0.5"},
+			want:         "198.51.100.9",
+		},
+		{name: "only trusted hops", remoteAddr: "10.0.0.2:5000", forwardedFor: []string{"10.0.0.3"}, want: "10.0.0.3"},
+		{name: "trusted proxy without header", remoteAddr: "10.0.0.2:5000", want: "10.0.0.2"},
+		{name: "ipv4-mapped proxy", remoteAddr: "[::ffff:10.0.0.2]:5000", forwardedFor: []string{"198.51.100.9"}, want: "198.51.100.9"},
+		{name: "no port", remoteAddr: "203.0.113.7", want: "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/oneLink/abc", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			if got := h.clientIP(r); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
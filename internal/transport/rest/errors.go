@@ -0,0 +1,128 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"short_link/internal/model"
+	"short_link/internal/service"
+	"time"
+)
+
+// Kinds of errors that only the HTTP layer produces.
+const (
+	kindUnsupportedMediaType service.Kind = "unsupported_media_type"
+	kindBodyTooLarge         service.Kind = "body_too_large"
+)
+
+// kindStatus is the single place where error kinds become HTTP statuses.
+// Errors of unknown kind answer 500.
+var kindStatus = map[service.Kind]int{
+	service.KindValidation:    http.StatusBadRequest,
+	service.KindUnauthorized:  http.StatusUnauthorized,
+	service.KindNotFound:      http.StatusNotFound,
+	service.KindConflict:      http.StatusConflict,
+	service.KindGone:          http.StatusGone,
+	service.KindUnprocessable: http.StatusUnprocessableEntity,
+	service.KindRateLimited:   http.StatusTooManyRequests,
+	service.KindUnavailable:   http.StatusServiceUnavailable,
+	kindUnsupportedMediaType:  http.StatusUnsupportedMediaType,
+	kindBodyTooLarge:          http.StatusRequestEntityTooLarge,
+}
+
+var (
+	errUnsupportedMediaType = service.NewError(kindUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json")
+	errBodyTooLarge         = service.NewError(kindBodyTooLarge, "body_too_large", "request body is too large")
+	errBodyInvalid          = service.NewError(service.KindValidation, "body_invalid", "request body is malformed")
+	errShortLinkRequired    = &service.Error{Kind: service.KindValidation, Code: "short_link_required", Field: "shortLink", Message: "short link is required"}
+	errHistoryIDInvalid     = &service.Error{Kind: service.KindValidation, Code: "history_id_invalid", Field: "historyID", Message: "history id must be a number"}
+	errExportFormat         = &service.Error{Kind: service.KindValidation, Code: "format_invalid", Field: "format", Message: "format must be csv or ndjson"}
+	errDisabledInvalid      = &service.Error{Kind: service.KindValidation, Code: "disabled_invalid", Field: "disabled", Message: "disabled must be true or false"}
+	errMinClicksInvalid     = &service.Error{Kind: service.KindValidation, Code: "min_clicks_invalid", Field: "min_clicks", Message: "min_clicks must be a number"}
+)
+
+// bodyError classifies a failure to read or decode the request body.
+func bodyError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return errBodyTooLarge
+	}
+
+	return fmt.Errorf("%w: %w", errBodyInvalid, err)
+}
+
+// SendErrorResponse answers with an application/problem+json body. The
+// status follows the kind of err; unexpected errors answer 500 without
+// exposing their text.
+func (h *HTTPHandler) SendErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	h.linksServ.Logger.Error(err.Error())
+
+	problem := newProblem(r, err)
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		h.linksServ.Logger.Error(err.Error())
+	}
+}
+
+func newProblem(r *http.Request, err error) model.ProblemDTO {
+	problem := model.ProblemDTO{
+		Type:     "about:blank",
+		Status:   http.StatusInternalServerError,
+		Detail:   "internal server error",
+		Instance: r.URL.Path,
+		Code:     string(service.KindInternal),
+		Time:     time.Now(),
+	}
+
+	if serviceErr := service.ErrorOf(err); serviceErr != nil {
+		if status, ok := kindStatus[serviceErr.Kind]; ok {
+			problem.Status = status
+			problem.Code = serviceErr.Code
+
+			// Validation details help the client fix its request; other
+			// errors may wrap internal causes, so only their own text is
+			// shown.
+			problem.Detail = serviceErr.Message
+			if serviceErr.Kind == service.KindValidation {
+				problem.Detail = err.Error()
+				problem.Errors = fieldErrors(err)
+			}
+		}
+	}
+
+	problem.Title = http.StatusText(problem.Status)
+
+	return problem
+}
+
+// fieldErrors collects the request fields named anywhere in the chain of
+// err, either by a FieldError or by an Error bound to a field.
+func fieldErrors(err error) []model.FieldErrorDTO {
+	switch e := err.(type) {
+	case *service.FieldError:
+		fieldErr := model.FieldErrorDTO{Field: e.Field, Message: e.Err.Error()}
+		if serviceErr := service.ErrorOf(e.Err); serviceErr != nil {
+			fieldErr.Code = serviceErr.Code
+		}
+		return []model.FieldErrorDTO{fieldErr}
+	case *service.Error:
+		if e.Field == "" {
+			return nil
+		}
+		return []model.FieldErrorDTO{{Field: e.Field, Code: e.Code, Message: e.Message}}
+	case interface{ Unwrap() []error }:
+		var fieldErrs []model.FieldErrorDTO
+		for _, wrapped := range e.Unwrap() {
+			fieldErrs = append(fieldErrs, fieldErrors(wrapped)...)
+		}
+		return fieldErrs
+	case interface{ Unwrap() error }:
+		return fieldErrors(e.Unwrap())
+	}
+
+	return nil
+}
@@ -0,0 +1,367 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"short_link/internal/model"
+	"short_link/internal/referrer"
+	"short_link/internal/service"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+
+	exportTimeout = 10 * time.Minute
+	// exportFlushRows is how many rows are written between flushes, so the
+	// client sees a steady stream instead of one response at the end.
+	exportFlushRows = 1000
+)
+
+type exportColumn[T any] struct {
+	name  string
+	value func(T) any
+}
+
+var linkExportColumns = []exportColumn[*model.LinkStatsDTO]{
+	{"short_url", func(l *model.LinkStatsDTO) any { return l.ShortURL }},
+	{"url", func(l *model.LinkStatsDTO) any { return l.URL }},
+	{"created_at", func(l *model.LinkStatsDTO) any { return l.CreatedAt }},
+	{"accessed_at", func(l *model.LinkStatsDTO) any { return optional(l.AccessedAt) }},
+	{"accessed_count", func(l *model.LinkStatsDTO) any { return l.AccessedCount }},
+	{"bot_count", func(l *model.LinkStatsDTO) any { return l.BotCount }},
+	{"expires_at", func(l *model.LinkStatsDTO) any { return optional(l.ExpiresAt) }},
+	{"max_clicks", func(l *model.LinkStatsDTO) any { return optional(l.MaxClicks) }},
+	{"password_protected", func(l *model.LinkStatsDTO) any { return l.Protected }},
+	{"active_from", func(l *model.LinkStatsDTO) any { return optional(l.ActiveFrom) }},
+	{"disabled", func(l *model.LinkStatsDTO) any { return l.Disabled }},
+}
+
+var clickExportColumns = []exportColumn[*model.ClickEvent]{
+	{"short_url", func(e *model.ClickEvent) any { return e.ShortURL }},
+	{"clicked_at", func(e *model.ClickEvent) any { return e.ClickedAt }},
+	{"referrer", func(e *model.ClickEvent) any { return e.Referrer }},
+	{"referrer_domain", func(e *model.ClickEvent) any { return referrer.Domain(e.Referrer) }},
+	{"user_agent", func(e *model.ClickEvent) any { return e.UserAgent }},
+	{"device_type", func(e *model.ClickEvent) any { return e.DeviceType }},
+	{"os_family", func(e *model.ClickEvent) any { return e.OSFamily }},
+	{"browser_family", func(e *model.ClickEvent) any { return e.BrowserFamily }},
+	{"is_bot", func(e *model.ClickEvent) any { return e.IsBot }},
+}
+
+// optional turns a nil pointer into an untyped nil, so it is written as an
+// empty CSV field or a JSON null.
+func optional[T any](v *T) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// exportEncoder writes rows as CSV or NDJSON. Headers are sent with the first
+// row, so an error that happens before any data can still be answered with
+// a regular error response.
+type exportEncoder[T any] struct {
+	w        http.ResponseWriter
+	format   string
+	filename string
+	columns  []exportColumn[T]
+	csv      *csv.Writer
+	rows     int
+	sent     bool
+}
+
+func newExportEncoder[T any](w http.ResponseWriter, format, filename string, columns []exportColumn[T]) *exportEncoder[T] {
+	return &exportEncoder[T]{w: w, format: format, filename: filename, columns: columns}
+}
+
+func (e *exportEncoder[T]) started() bool {
+	return e.sent
+}
+
+func (e *exportEncoder[T]) start() error {
+	e.sent = true
+
+	contentType := "application/x-ndjson"
+	if e.format == formatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+
+	e.w.Header().Set("Content-Type", contentType)
+	e.w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, e.filename, e.format))
+	e.w.WriteHeader(http.StatusOK)
+
+	if e.format != formatCSV {
+		return nil
+	}
+
+	e.csv = csv.NewWriter(e.w)
+
+	header := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		header[i] = column.name
+	}
+
+	return e.csv.Write(header)
+}
+
+func (e *exportEncoder[T]) Write(row T) error {
+	if !e.started() {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if e.format == formatCSV {
+		err = e.writeCSV(row)
+	} else {
+		err = e.writeJSON(row)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	e.rows++
+	if e.rows%exportFlushRows == 0 {
+		return e.flush()
+	}
+
+	return nil
+}
+
+func (e *exportEncoder[T]) writeCSV(row T) error {
+	record := make([]string, len(e.columns))
+
+	for i, column := range e.columns {
+		switch v := column.value(row).(type) {
+		case nil:
+		case time.Time:
+			record[i] = v.Format(time.RFC3339)
+		case string:
+			record[i] = escapeFormula(v)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+
+	return e.csv.Write(record)
+}
+
+// escapeFormula keeps spreadsheets from running a cell as a formula. URLs,
+// referrers and user agents come from clients, so a text cell starting with
+// a formula character is prefixed with a quote.
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+
+	return value
+}
+
+// writeJSON writes one object per line with the keys in column order.
+func (e *exportEncoder[T]) writeJSON(row T) error {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+	for i, column := range e.columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		value, err := json.Marshal(column.value(row))
+		if err != nil {
+			return err
+		}
+
+		buf.WriteString(strconv.Quote(column.name))
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteString("}\n")
+
+	_, err := e.w.Write(buf.Bytes())
+
+	return err
+}
+
+func (e *exportEncoder[T]) flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+
+	if flusher, ok := e.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return nil
+}
+
+// Close sends the headers of an empty export and flushes the rest.
+func (e *exportEncoder[T]) Close() error {
+	if !e.started() {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	return e.flush()
+}
+
+// exportFormat picks the export format from the format parameter, falling
+// back to the Accept header and then to CSV.
+func exportFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if format != formatCSV && format != formatNDJSON {
+			return "", errExportFormat
+		}
+		return format, nil
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case "text/csv":
+			return formatCSV, nil
+		case "application/x-ndjson", "application/ndjson", "application/jsonl":
+			return formatNDJSON, nil
+		}
+	}
+
+	return formatCSV, nil
+}
+
+// extendWriteDeadline lets an export stream past the server write timeout,
+// up to exportTimeout.
+func extendWriteDeadline(w http.ResponseWriter) {
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(exportTimeout))
+}
+
+func (h *HTTPHandler) HandleExportLinks(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), exportTimeout)
+	defer cancel()
+
+	extendWriteDeadline(w)
+
+	format, err := exportFormat(r)
+	if err != nil {
+		h.SendErrorResponse(w, r, err)
+		return
+	}
+
+	filter, err := parseLinkFilter(r)
+	if err != nil {
+		h.SendErrorResponse(w, r, err)
+		return
+	}
+
+	encoder := newExportEncoder(w, format, "links", linkExportColumns)
+
+	err = h.linksServ.ExportLinks(ctx, filter, encoder.Write)
+	h.finishExport(w, r, encoder.started(), encoder.Close, err)
+}
+
+func (h *HTTPHandler) HandleExportClicks(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), exportTimeout)
+	defer cancel()
+
+	extendWriteDeadline(w)
+
+	format, err := exportFormat(r)
+	if err != nil {
+		h.SendErrorResponse(w, r, err)
+		return
+	}
+
+	shortLink := mux.Vars(r)["shortLink"]
+	params := r.URL.Query()
+
+	encoder := newExportEncoder(w, format, "clicks-"+shortLink, clickExportColumns)
+
+	err = h.linksServ.ExportClicks(ctx, shortLink, model.ClickStatsQuery{
+		From:     params.Get("from"),
+		To:       params.Get("to"),
+		TimeZone: params.Get("tz"),
+	}, encoder.Write)
+	h.finishExport(w, r, encoder.started(), encoder.Close, err)
+}
+
+// finishExport completes an export. Errors before the first row get a
+// regular error response; once rows were sent the status is already out, so
+// the stream is cut short and the error is only logged.
+func (h *HTTPHandler) finishExport(w http.ResponseWriter, r *http.Request, started bool, close func() error, err error) {
+	if err != nil {
+		if !started {
+			h.SendErrorResponse(w, r, err)
+			return
+		}
+
+		h.linksServ.Logger.Error("export aborted: " + err.Error())
+		return
+	}
+
+	if err := close(); err != nil {
+		h.linksServ.Logger.Error("export aborted: " + err.Error())
+	}
+}
+
+func parseLinkFilter(r *http.Request) (model.LinkFilter, error) {
+	var (
+		filter model.LinkFilter
+		params = r.URL.Query()
+	)
+
+	if value := params.Get("created_from"); value != "" {
+		t, err := service.ParseTime(value, time.UTC)
+		if err != nil {
+			return filter, service.WithField("created_from", err)
+		}
+		filter.CreatedFrom = &t
+	}
+
+	if value := params.Get("created_to"); value != "" {
+		t, err := service.ParseTime(value, time.UTC)
+		if err != nil {
+			return filter, service.WithField("created_to", err)
+		}
+		filter.CreatedTo = &t
+	}
+
+	if value := params.Get("disabled"); value != "" {
+		disabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errDisabledInvalid
+		}
+		filter.Disabled = &disabled
+	}
+
+	if value := params.Get("min_clicks"); value != "" {
+		minClicks, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, errMinClicksInvalid
+		}
+		filter.MinClicks = &minClicks
+	}
+
+	filter.Host = params.Get("domain")
+
+	return filter, nil
+}
@@ -0,0 +1,29 @@
+package rest
+
+import "testing"
+
+func TestEscapeFormula(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"https://example.com", "https://example.com"},
+		{"Mozilla/5.0", "Mozilla/5.0"},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1+1", "'+1+1"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "'\r=1"},
+		{"a=1", "a=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := escapeFormula(tt.value); got != tt.want {
+				t.Errorf("escapeFormula(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
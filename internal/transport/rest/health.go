@@ -0,0 +1,48 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"short_link/internal/health"
+	"short_link/internal/model"
+	"time"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// HandleHealthz reports that the process is alive. It checks no
+// dependencies, so a database outage does not get the instance restarted.
+func (h *HealthHandler) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, model.HealthDTO{Status: health.StatusOK})
+}
+
+// HandleReadyz reports whether the instance can serve traffic, with the
+// status and latency of every dependency.
+func (h *HealthHandler) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	result, ready := h.checker.Ready(ctx)
+
+	statusCode := http.StatusOK
+	if !ready {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	writeHealth(w, statusCode, result)
+}
+
+func writeHealth(w http.ResponseWriter, statusCode int, result model.HealthDTO) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+
+	json.NewEncoder(w).Encode(result)
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"net"
 	"net/http"
+	"net/netip"
 	"net/url"
+	"short_link/config"
 	"short_link/internal/logger"
 	"short_link/internal/model"
 	"short_link/internal/service"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,84 +20,174 @@ import (
 )
 
 type HTTPHandler struct {
-	linksServ *service.LinkService
+	linksServ      *service.LinkService
+	redirectCfg    config.RedirectConfig
+	trustedProxies []netip.Prefix
 }
 
-func NewHTTPHanler(linksServ *service.LinkService) *HTTPHandler {
+func NewHTTPHanler(linksServ *service.LinkService, redirectCfg config.RedirectConfig, trustedProxies []netip.Prefix) *HTTPHandler {
 	return &HTTPHandler{
-		linksServ: linksServ,
+		linksServ:      linksServ,
+		redirectCfg:    redirectCfg,
+		trustedProxies: trustedProxies,
 	}
 }
 
-func (h *HTTPHandler) SendErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+func (h *HTTPHandler) HandleCreateShortLink(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
 
-	h.linksServ.Logger.Error(message)
+	if !strings.Contains(contentType, "application/json") {
+		h.SendErrorResponse(w, r, errUnsupportedMediaType)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+
+	var linkDTO model.LinkDTO
 
-	errDTO := model.ErrorDTO{
-		Message: message,
-		Time:    time.Now(),
+	if err := json.NewDecoder(r.Body).Decode(&linkDTO); err != nil {
+		h.SendErrorResponse(w, r, bodyError(err))
+		return
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	link, err := h.linksServ.Create(ctx, linkDTO, r.Header.Get("Idempotency-Key"))
+
+	if err != nil {
+		h.SendErrorResponse(w, r, err)
+		return
+	}
+
+	link.ShortURL = buildShortURL(r, r.URL.Path, link.ShortURL)
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+	w.WriteHeader(http.StatusCreated)
 
-	if err := json.NewEncoder(w).Encode(errDTO); err != nil {
-		h.linksServ.Logger.Error(err.Error())
+	if err := json.NewEncoder(w).Encode(link); err != nil {
+		h.linksServ.Logger.Error(err.Error(), logger.String("original_url", link.URL), logger.String("short_url", link.ShortURL))
 	}
+
 }
 
-func (h *HTTPHandler) HandleCreateShortLink(w http.ResponseWriter, r *http.Request) {
+func (h *HTTPHandler) HandleCreateShortLinkBatch(w http.ResponseWriter, r *http.Request) {
 	contentType := r.Header.Get("Content-Type")
 
 	if !strings.Contains(contentType, "application/json") {
-		h.SendErrorResponse(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+		h.SendErrorResponse(w, r, errUnsupportedMediaType)
 		return
 	}
 
-	r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+	r.Body = http.MaxBytesReader(w, r.Body, 4*1048576)
 
-	var linkDTO model.LinkDTO
+	var batchDTO model.BatchLinkDTO
 
-	if err := json.NewDecoder(r.Body).Decode(&linkDTO); err != nil {
-		h.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+	if err := json.NewDecoder(r.Body).Decode(&batchDTO); err != nil {
+		h.SendErrorResponse(w, r, bodyError(err))
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	link, err := h.linksServ.Create(ctx, linkDTO.URL)
+	results, err := h.linksServ.CreateBatch(ctx, batchDTO.URLs)
 
 	if err != nil {
-		h.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		h.SendErrorResponse(w, r, err)
 		return
 	}
 
-	fullURL := &url.URL{
-		Scheme: "http",
-		Host:   r.Host,
-		Path:   r.URL.Path + "/" + link.ShortURL,
-	}
+	basePath := strings.TrimSuffix(r.URL.Path, "/batch")
 
-	link.ShortURL = fullURL.String()
+	for i := range results {
+		if results[i].ShortURL != "" {
+			results[i].ShortURL = buildShortURL(r, basePath, results[i].ShortURL)
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusOK)
 
-	if err := json.NewEncoder(w).Encode(link); err != nil {
-		h.linksServ.Logger.Error(err.Error(), logger.String("original_url", link.URL), logger.String("short_url", link.ShortURL))
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		h.linksServ.Logger.Error(err.Error())
+	}
+}
+
+// listingParams select a page of the link listing. A request without any of
+// them is answered with the bare array of all links, the shape the listing
+// had before it was paginated.
+var listingParams = []string{"limit", "cursor", "sort", "order", "created_from", "created_to", "domain", "min_clicks", "disabled"}
+
+func isPaginatedListing(r *http.Request) bool {
+	params := r.URL.Query()
+
+	for _, name := range listingParams {
+		if params.Has(name) {
+			return true
+		}
 	}
 
+	return false
 }
 
 func (h *HTTPHandler) HandleGetAllShortLink(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	links, err := h.linksServ.GetAllShortLink(ctx)
+	var expandReferrers bool
 
+	for _, expand := range strings.Split(r.URL.Query().Get("expand"), ",") {
+		switch strings.TrimSpace(expand) {
+		case "":
+		case "referrers":
+			expandReferrers = true
+		default:
+			h.SendErrorResponse(w, r, service.ErrExpandInvalid)
+			return
+		}
+	}
+
+	if !isPaginatedListing(r) {
+		links, err := h.linksServ.GetAllShortLinks(ctx, expandReferrers)
+
+		if err != nil {
+			h.SendErrorResponse(w, r, err)
+			return
+		}
+
+		w.Header().Set("Deprecation", "true")
+		h.writeJSON(w, http.StatusOK, links)
+		return
+	}
+
+	filter, err := parseLinkFilter(r)
 	if err != nil {
-		h.linksServ.Logger.Error(err.Error())
+		h.SendErrorResponse(w, r, err)
+		return
+	}
+
+	params := r.URL.Query()
+
+	var limit int
+
+	if value := params.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil {
+			h.SendErrorResponse(w, r, service.ErrLimitInvalid)
+			return
+		}
+	}
+
+	links, err := h.linksServ.GetAllShortLink(ctx, model.LinkListQuery{
+		Filter: filter,
+		Sort:   params.Get("sort"),
+		Order:  params.Get("order"),
+		Cursor: params.Get("cursor"),
+		Limit:  limit,
+	}, expandReferrers)
+
+	if err != nil {
+		h.SendErrorResponse(w, r, err)
 		return
 	}
 
@@ -105,6 +199,33 @@ func (h *HTTPHandler) HandleGetAllShortLink(w http.ResponseWriter, r *http.Reque
 	}
 }
 
+func (h *HTTPHandler) HandleGetTopLinks(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	params := r.URL.Query()
+
+	var limit int
+
+	if value := params.Get("limit"); value != "" {
+		var err error
+
+		if limit, err = strconv.Atoi(value); err != nil {
+			h.SendErrorResponse(w, r, service.ErrLimitInvalid)
+			return
+		}
+	}
+
+	top, err := h.linksServ.GetTopLinks(ctx, params.Get("window"), limit)
+
+	if err != nil {
+		h.SendErrorResponse(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, top)
+}
+
 func (h *HTTPHandler) HandleRedirection(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -112,20 +233,349 @@ func (h *HTTPHandler) HandleRedirection(w http.ResponseWriter, r *http.Request)
 	shortLink := mux.Vars(r)["shortLink"]
 
 	if shortLink == "" {
-		h.SendErrorResponse(w, http.StatusBadRequest, "Short link is required")
+		h.SendErrorResponse(w, r, errShortLinkRequired)
 		return
 	}
 
-	link, err := h.linksServ.GetOriginalLink(ctx, shortLink)
+	visit := h.newVisit(r, shortLink)
+
+	link, err := h.linksServ.GetOriginalLink(ctx, visit)
 
 	if err != nil {
-		if errors.Is(err, service.ErrLinkBadRequest) {
-			h.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		if errors.Is(err, service.ErrPasswordRequired) {
+			h.sendPasswordForm(w, r, http.StatusOK, "")
 		} else {
-			h.SendErrorResponse(w, http.StatusInternalServerError, "Failed to get original link")
+			h.sendResolveError(w, r, err)
 		}
 		return
 	}
 
+	h.linksServ.RecordClick(ctx, visit)
+
 	http.Redirect(w, r, *link, http.StatusFound)
 }
+
+func (h *HTTPHandler) HandleUnlock(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shortLink := mux.Vars(r)["shortLink"]
+
+	if shortLink == "" {
+		h.SendErrorResponse(w, r, errShortLinkRequired)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 4096)
+
+	if err := r.ParseForm(); err != nil {
+		h.SendErrorResponse(w, r, bodyError(err))
+		return
+	}
+
+	visit := h.newVisit(r, shortLink)
+
+	link, err := h.linksServ.UnlockLink(ctx, visit, r.PostForm.Get("password"))
+
+	if err != nil {
+		if errors.Is(err, service.ErrPasswordIncorrect) {
+			h.sendPasswordForm(w, r, http.StatusUnauthorized, err.Error())
+		} else if errors.Is(err, service.ErrTooManyPasswordAttempts) {
+			h.sendPasswordForm(w, r, http.StatusTooManyRequests, err.Error())
+		} else {
+			h.sendResolveError(w, r, err)
+		}
+		return
+	}
+
+	h.linksServ.RecordClick(ctx, visit)
+
+	http.Redirect(w, r, *link, http.StatusSeeOther)
+}
+
+func (h *HTTPHandler) newVisit(r *http.Request, shortLink string) service.Visit {
+	return service.Visit{
+		ShortURL:       shortLink,
+		Referrer:       r.Referer(),
+		UserAgent:      r.UserAgent(),
+		AcceptLanguage: r.Header.Get("Accept-Language"),
+		ClientIP:       h.clientIP(r),
+	}
+}
+
+func (h *HTTPHandler) HandleUpdateShortLink(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+
+	if !strings.Contains(contentType, "application/json") {
+		h.SendErrorResponse(w, r, errUnsupportedMediaType)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+
+	var updateDTO model.UpdateLinkDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&updateDTO); err != nil {
+		h.SendErrorResponse(w, r, bodyError(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	link, err := h.linksServ.UpdateDestination(ctx, mux.Vars(r)["shortLink"], updateDTO.URL)
+
+	if err != nil {
+		h.SendErrorResponse(w, r, err)
+		return
+	}
+
+	link.ShortURL = buildShortURL(r, linksPath, link.ShortURL)
+
+	h.writeJSON(w, http.StatusOK, link)
+}
+
+func (h *HTTPHandler) HandleGetLinkHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	history, err := h.linksServ.GetHistory(ctx, mux.Vars(r)["shortLink"])
+
+	if err != nil {
+		h.SendErrorResponse(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, history)
+}
+
+func (h *HTTPHandler) HandleGetClickStats(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	params := r.URL.Query()
+
+	stats, err := h.linksServ.GetClickStats(ctx, mux.Vars(r)["shortLink"], model.ClickStatsQuery{
+		From:     params.Get("from"),
+		To:       params.Get("to"),
+		Interval: params.Get("interval"),
+		TimeZone: params.Get("tz"),
+	})
+
+	if err != nil {
+		h.SendErrorResponse(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, stats)
+}
+
+func (h *HTTPHandler) HandleGetClickBreakdown(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	params := r.URL.Query()
+
+	breakdown, err := h.linksServ.GetClickBreakdown(ctx, mux.Vars(r)["shortLink"], model.ClickStatsQuery{
+		From:     params.Get("from"),
+		To:       params.Get("to"),
+		TimeZone: params.Get("tz"),
+	})
+
+	if err != nil {
+		h.SendErrorResponse(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, breakdown)
+}
+
+func (h *HTTPHandler) HandleGetTopReferrers(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	params := r.URL.Query()
+
+	var limit int
+
+	if value := params.Get("limit"); value != "" {
+		var err error
+
+		if limit, err = strconv.Atoi(value); err != nil {
+			h.SendErrorResponse(w, r, service.ErrLimitInvalid)
+			return
+		}
+	}
+
+	referrers, err := h.linksServ.GetTopReferrers(ctx, mux.Vars(r)["shortLink"], model.ClickStatsQuery{
+		From:     params.Get("from"),
+		To:       params.Get("to"),
+		TimeZone: params.Get("tz"),
+	}, limit)
+
+	if err != nil {
+		h.SendErrorResponse(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, referrers)
+}
+
+func (h *HTTPHandler) HandleGetLinkInfo(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	info, err := h.linksServ.GetLinkInfo(ctx, mux.Vars(r)["shortLink"])
+
+	if err != nil {
+		h.SendErrorResponse(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, info)
+}
+
+func (h *HTTPHandler) HandleRollbackShortLink(w http.ResponseWriter, r *http.Request) {
+	historyID, err := strconv.ParseInt(mux.Vars(r)["historyID"], 10, 64)
+
+	if err != nil {
+		h.SendErrorResponse(w, r, errHistoryIDInvalid)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	link, err := h.linksServ.RollbackDestination(ctx, mux.Vars(r)["shortLink"], historyID)
+
+	if err != nil {
+		h.SendErrorResponse(w, r, err)
+		return
+	}
+
+	link.ShortURL = buildShortURL(r, linksPath, link.ShortURL)
+
+	h.writeJSON(w, http.StatusOK, link)
+}
+
+func (h *HTTPHandler) HandleDeleteShortLink(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.linksServ.DeleteLink(ctx, mux.Vars(r)["shortLink"]); err != nil {
+		h.SendErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPHandler) HandleRestoreShortLink(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.linksServ.RestoreLink(ctx, mux.Vars(r)["shortLink"]); err != nil {
+		h.SendErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPHandler) HandleDisableShortLink(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, true)
+}
+
+func (h *HTTPHandler) HandleEnableShortLink(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, false)
+}
+
+func (h *HTTPHandler) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.linksServ.SetLinkDisabled(ctx, mux.Vars(r)["shortLink"], disabled); err != nil {
+		h.SendErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendResolveError answers a failed attempt to follow a short link. Links
+// that are not active yet may be sent to the configured fallback instead.
+func (h *HTTPHandler) sendResolveError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, service.ErrLinkNotActive) && h.redirectCfg.InactiveFallbackURL != "" {
+		http.Redirect(w, r, h.redirectCfg.InactiveFallbackURL, http.StatusFound)
+		return
+	}
+
+	h.SendErrorResponse(w, r, err)
+}
+
+func buildShortURL(r *http.Request, basePath, shortLink string) string {
+	fullURL := &url.URL{
+		Scheme: "http",
+		Host:   r.Host,
+		Path:   basePath + "/" + shortLink,
+	}
+
+	return fullURL.String()
+}
+
+func (h *HTTPHandler) writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.linksServ.Logger.Error(err.Error())
+	}
+}
+
+// clientIP returns the address of the client. X-Forwarded-For is only
+// believed when the peer is a trusted proxy; it is then read from the right,
+// skipping trusted proxies, so entries a client prepended are ignored.
+func (h *HTTPHandler) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !h.isTrustedProxy(host) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+
+		if !h.isTrustedProxy(hop) {
+			return hop
+		}
+
+		host = hop
+	}
+
+	return host
+}
+
+func (h *HTTPHandler) isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+
+	for _, prefix := range h.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,34 @@
+package rest
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsPaginatedListing(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", false},
+		{"?expand=referrers", false},
+		{"?limit=20", true},
+		{"?cursor=abc", true},
+		{"?sort=clicks", true},
+		{"?order=asc", true},
+		{"?domain=example.com", true},
+		{"?min_clicks=0", true},
+		{"?disabled=false&expand=referrers", true},
+		{"?limit=", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/oneLink"+tt.query, nil)
+
+			if got := isPaginatedListing(r); got != tt.want {
+				t.Errorf("isPaginatedListing(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package rest
+
+import (
+	"html/template"
+	"net/http"
+)
+
+var passwordFormTemplate = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Protected link</title>
+</head>
+<body>
+<form method="POST" action="{{.Action}}">
+<p>This link is password protected.</p>
+{{if .Error}}<p style="color: red">{{.Error}}</p>{{end}}
+<input type="password" name="password" autofocus required>
+<button type="submit">Open</button>
+</form>
+</body>
+</html>
+`))
+
+type passwordForm struct {
+	Action string
+	Error  string
+}
+
+func (h *HTTPHandler) sendPasswordForm(w http.ResponseWriter, r *http.Request, statusCode int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+
+	form := passwordForm{
+		Action: r.URL.Path,
+		Error:  message,
+	}
+
+	if err := passwordFormTemplate.Execute(w, form); err != nil {
+		h.linksServ.Logger.Error(err.Error())
+	}
+}
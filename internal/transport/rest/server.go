@@ -1,30 +1,76 @@
 package rest
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"short_link/config"
+	"short_link/internal/metrics"
 
 	"github.com/gorilla/mux"
 )
 
+const linksPath = "/oneLink"
+
 type HTTPServer struct {
-	httpHandler *HTTPHandler
+	httpHandler   *HTTPHandler
+	healthHandler *HealthHandler
+	server        *http.Server
 }
 
-func NewServer(httpHandler *HTTPHandler) *HTTPServer {
-	return &HTTPServer{
-		httpHandler: httpHandler,
+func NewServer(httpHandler *HTTPHandler, healthHandler *HealthHandler, cfg config.HTTPConfig) *HTTPServer {
+	s := &HTTPServer{
+		httpHandler:   httpHandler,
+		healthHandler: healthHandler,
+	}
+
+	s.server = &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           s.routes(),
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
 	}
+
+	return s
 }
 
-func (s *HTTPServer) StartServer() error {
+func (s *HTTPServer) routes() http.Handler {
 	router := mux.NewRouter()
 
-	router.Path("/oneLink").Methods("POST").HandlerFunc(s.httpHandler.HandleCreateShortLink)
-	router.Path("/oneLink").Methods("GET").HandlerFunc(s.httpHandler.HandleGetAllShortLink)
-	router.Path("/oneLink/{shortLink}").Methods("GET").HandlerFunc(s.httpHandler.HandleRedirection)
+	router.Path("/metrics").Methods("GET").Handler(metrics.Handler())
+	router.Path("/healthz").Methods("GET").HandlerFunc(s.healthHandler.HandleHealthz)
+	router.Path("/readyz").Methods("GET").HandlerFunc(s.healthHandler.HandleReadyz)
+
+	router.Path(linksPath).Methods("POST").HandlerFunc(metrics.Instrument("create", s.httpHandler.HandleCreateShortLink))
+	router.Path(linksPath + "/batch").Methods("POST").HandlerFunc(metrics.Instrument("create_batch", s.httpHandler.HandleCreateShortLinkBatch))
+	router.Path(linksPath).Methods("GET").HandlerFunc(s.httpHandler.HandleGetAllShortLink)
+	router.Path(linksPath + "/export").Methods("GET").HandlerFunc(s.httpHandler.HandleExportLinks)
+	router.Path(linksPath + "/top").Methods("GET").HandlerFunc(s.httpHandler.HandleGetTopLinks)
+	router.Path(linksPath + "/{shortLink}").Methods("GET").HandlerFunc(metrics.Instrument("redirect", s.httpHandler.HandleRedirection))
+	router.Path(linksPath + "/{shortLink}").Methods("POST").HandlerFunc(metrics.Instrument("unlock", s.httpHandler.HandleUnlock))
+	router.Path(linksPath + "/{shortLink}").Methods("PATCH").HandlerFunc(s.httpHandler.HandleUpdateShortLink)
+	router.Path(linksPath + "/{shortLink}").Methods("DELETE").HandlerFunc(s.httpHandler.HandleDeleteShortLink)
+	router.Path(linksPath + "/{shortLink}/restore").Methods("POST").HandlerFunc(s.httpHandler.HandleRestoreShortLink)
+	router.Path(linksPath + "/{shortLink}/disable").Methods("POST").HandlerFunc(s.httpHandler.HandleDisableShortLink)
+	router.Path(linksPath + "/{shortLink}/enable").Methods("POST").HandlerFunc(s.httpHandler.HandleEnableShortLink)
+	router.Path(linksPath + "/{shortLink}/info").Methods("GET").HandlerFunc(s.httpHandler.HandleGetLinkInfo)
+	router.Path(linksPath + "/{shortLink}/stats").Methods("GET").HandlerFunc(s.httpHandler.HandleGetClickStats)
+	router.Path(linksPath + "/{shortLink}/stats/devices").Methods("GET").HandlerFunc(s.httpHandler.HandleGetClickBreakdown)
+	router.Path(linksPath + "/{shortLink}/stats/referrers").Methods("GET").HandlerFunc(s.httpHandler.HandleGetTopReferrers)
+	router.Path(linksPath + "/{shortLink}/export").Methods("GET").HandlerFunc(s.httpHandler.HandleExportClicks)
+	router.Path(linksPath + "/{shortLink}/history").Methods("GET").HandlerFunc(s.httpHandler.HandleGetLinkHistory)
+	router.Path(linksPath + "/{shortLink}/history/{historyID:[0-9]+}/rollback").Methods("POST").HandlerFunc(s.httpHandler.HandleRollbackShortLink)
+
+	return router
+}
 
-	if err := http.ListenAndServe(":8080", router); err != nil {
+// StartServer serves until Shutdown is called, which is reported as a
+// regular stop.
+func (s *HTTPServer) StartServer() error {
+	if err := s.server.ListenAndServe(); err != nil {
 		if errors.Is(err, http.ErrServerClosed) {
 			return nil
 		}
@@ -34,3 +80,15 @@ func (s *HTTPServer) StartServer() error {
 
 	return nil
 }
+
+// Shutdown stops accepting connections and waits for in-flight requests
+// until ctx is done. Requests still running then have their connections
+// closed.
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.server.Close()
+		return err
+	}
+
+	return nil
+}
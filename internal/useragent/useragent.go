@@ -0,0 +1,179 @@
+// Package useragent classifies User-Agent headers into device type, OS family
+// and browser family. It only looks for well known tokens, which is enough
+// for aggregated click reports and cheap enough for the redirect path.
+package useragent
+
+import (
+	_ "embed"
+	"strings"
+)
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+
+	Unknown = "unknown"
+	Other   = "Other"
+)
+
+type Info struct {
+	DeviceType string
+	OS         string
+	Browser    string
+}
+
+type rule struct {
+	tokens []string
+	family string
+}
+
+// Rules are checked in order, so tokens shared with other products must come
+// after the more specific ones: Edge and Opera also announce Chrome, and
+// every Chromium browser announces Safari.
+var osRules = []rule{
+	{[]string{"windows phone"}, "Windows Phone"},
+	{[]string{"iphone", "ipad", "ipod"}, "iOS"},
+	{[]string{"android"}, "Android"},
+	{[]string{"cros"}, "ChromeOS"},
+	{[]string{"windows"}, "Windows"},
+	{[]string{"macintosh", "mac os x"}, "macOS"},
+	{[]string{"linux"}, "Linux"},
+}
+
+var browserRules = []rule{
+	{[]string{"edg/", "edge/", "edga/", "edgios/"}, "Edge"},
+	{[]string{"opr/", "opera"}, "Opera"},
+	{[]string{"samsungbrowser/"}, "Samsung Internet"},
+	{[]string{"yabrowser/"}, "Yandex Browser"},
+	{[]string{"firefox/", "fxios/"}, "Firefox"},
+	{[]string{"chrome/", "crios/", "chromium/"}, "Chrome"},
+	{[]string{"msie ", "trident/"}, "Internet Explorer"},
+	{[]string{"safari/"}, "Safari"},
+}
+
+//go:embed bots.txt
+var botList string
+
+var botTokens = parseBotList(botList)
+
+// genericBotWords catch bots missing from bots.txt. They match only as a
+// whole word or at the end of a product name followed by a version, as in
+// "ExampleBot/1.0", so device names such as "CUBOT X30" are not bots.
+var genericBotWords = []string{"bot", "crawler", "spider", "slurp", "preview"}
+
+func parseBotList(list string) []string {
+	var tokens []string
+
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tokens = append(tokens, line)
+	}
+
+	return tokens
+}
+
+// IsBot reports whether a request comes from a bot rather than a person. A
+// request is a bot when its User-Agent is empty or has a bot signature, or
+// when it has no Accept-Language and does not announce itself as a browser.
+// Every browser sends Accept-Language, but privacy extensions may strip it,
+// so its absence alone is not enough.
+func IsBot(userAgent, acceptLanguage string) bool {
+	if strings.TrimSpace(userAgent) == "" {
+		return true
+	}
+
+	ua := strings.ToLower(userAgent)
+
+	if hasBotSignature(ua) {
+		return true
+	}
+
+	return strings.TrimSpace(acceptLanguage) == "" && !strings.HasPrefix(ua, "mozilla/")
+}
+
+// hasBotSignature reports whether the lowercase User-Agent ua matches
+// bots.txt or contains a generic bot word.
+func hasBotSignature(ua string) bool {
+	if containsAny(ua, botTokens) {
+		return true
+	}
+
+	for _, word := range genericBotWords {
+		for i := 0; i < len(ua); {
+			j := strings.Index(ua[i:], word)
+			if j < 0 {
+				break
+			}
+
+			start, end := i+j, i+j+len(word)
+			wordEnds := end == len(ua) || !isLetter(ua[end])
+			wordStarts := start == 0 || !isLetter(ua[start-1])
+			versioned := end < len(ua) && ua[end] == '/'
+
+			if wordEnds && wordStarts || versioned {
+				return true
+			}
+
+			i = end
+		}
+	}
+
+	return false
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
+
+// Parse classifies a User-Agent header. An empty header yields Unknown for
+// every field, unrecognized products yield Other.
+func Parse(userAgent string) Info {
+	if strings.TrimSpace(userAgent) == "" {
+		return Info{DeviceType: Unknown, OS: Unknown, Browser: Unknown}
+	}
+
+	ua := strings.ToLower(userAgent)
+
+	info := Info{
+		OS:      match(ua, osRules),
+		Browser: match(ua, browserRules),
+	}
+
+	switch {
+	case hasBotSignature(ua):
+		info.DeviceType = DeviceBot
+	case containsAny(ua, []string{"ipad", "tablet"}) || info.OS == "Android" && !strings.Contains(ua, "mobile"):
+		info.DeviceType = DeviceTablet
+	case containsAny(ua, []string{"mobi", "iphone", "ipod", "windows phone"}):
+		info.DeviceType = DeviceMobile
+	default:
+		info.DeviceType = DeviceDesktop
+	}
+
+	return info
+}
+
+func match(ua string, rules []rule) string {
+	for _, r := range rules {
+		if containsAny(ua, r.tokens) {
+			return r.family
+		}
+	}
+
+	return Other
+}
+
+func containsAny(s string, tokens []string) bool {
+	for _, token := range tokens {
+		if strings.Contains(s, token) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,76 @@
+package useragent
+
+import "testing"
+
+const (
+	chromeWindows  = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+	safariIPhone   = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+	chromeAndroid  = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36"
+	chromeTablet   = "Mozilla/5.0 (Linux; Android 13; SM-X710) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+	edgeWindows    = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.51"
+	firefoxLinux   = "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0"
+	safariMac      = "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15"
+	cubotPhone     = "Mozilla/5.0 (Linux; Android 10; CUBOT X30) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36"
+	googlebot      = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+	telegramBot    = "TelegramBot (like TwitterBot)"
+	unknownCrawler = "Mozilla/5.0 (compatible; ExampleBot/1.0; +https://example.com)"
+	curl           = "curl/8.5.0"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      Info
+	}{
+		{"empty", "", Info{DeviceType: Unknown, OS: Unknown, Browser: Unknown}},
+		{"chrome on windows", chromeWindows, Info{DeviceType: DeviceDesktop, OS: "Windows", Browser: "Chrome"}},
+		{"safari on iphone", safariIPhone, Info{DeviceType: DeviceMobile, OS: "iOS", Browser: "Safari"}},
+		{"chrome on android phone", chromeAndroid, Info{DeviceType: DeviceMobile, OS: "Android", Browser: "Chrome"}},
+		{"chrome on android tablet", chromeTablet, Info{DeviceType: DeviceTablet, OS: "Android", Browser: "Chrome"}},
+		{"edge announces chrome", edgeWindows, Info{DeviceType: DeviceDesktop, OS: "Windows", Browser: "Edge"}},
+		{"firefox on linux", firefoxLinux, Info{DeviceType: DeviceDesktop, OS: "Linux", Browser: "Firefox"}},
+		{"safari on mac", safariMac, Info{DeviceType: DeviceDesktop, OS: "macOS", Browser: "Safari"}},
+		{"cubot phone", cubotPhone, Info{DeviceType: DeviceMobile, OS: "Android", Browser: "Chrome"}},
+		{"googlebot", googlebot, Info{DeviceType: DeviceBot, OS: Other, Browser: Other}},
+		{"curl", curl, Info{DeviceType: DeviceBot, OS: Other, Browser: Other}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.userAgent); got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.userAgent, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBot(t *testing.T) {
+	tests := []struct {
+		name           string
+		userAgent      string
+		acceptLanguage string
+		want           bool
+	}{
+		{"browser", chromeWindows, "en-US,en;q=0.9", false},
+		{"browser without accept-language", chromeWindows, "", false},
+		{"cubot phone", cubotPhone, "ru-RU", false},
+		{"empty user agent", "", "en", true},
+		{"googlebot", googlebot, "", true},
+		{"telegram preview", telegramBot, "", true},
+		{"unknown crawler by generic word", unknownCrawler, "en", true},
+		{"generic word as a whole word", "Mozilla/5.0 (compatible; bot)", "en", true},
+		{"http library", curl, "", true},
+		{"non-browser without accept-language", "ExampleApp/2.3", "", true},
+		{"non-browser with accept-language", "ExampleApp/2.3", "de", false},
+		{"bot inside a word", "Mozilla/5.0 (Linux; Android 12; Robotech R1) Chrome/124.0", "en", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBot(tt.userAgent, tt.acceptLanguage); got != tt.want {
+				t.Errorf("IsBot(%q, %q) = %v, want %v", tt.userAgent, tt.acceptLanguage, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package worker
 import (
 	"context"
 	"short_link/internal/logger"
+	"short_link/internal/metrics"
 	"short_link/internal/repository/database"
 	"time"
 )
@@ -51,6 +52,11 @@ func (c *Cleaner) runCleanup() {
 
 	c.logger.Info("Starting cleanup")
 
+	start := time.Now()
+	defer func() {
+		metrics.CleanerRunDuration.Observe(time.Since(start).Seconds())
+	}()
+
 	tx, err := c.repo.BeginTx(ctx)
 	if err != nil {
 		c.logger.Error("Failed to begin transaction",
@@ -73,13 +79,19 @@ func (c *Cleaner) runCleanup() {
 		return
 	}
 
-	err = c.repo.DeleteExpiredShortLinks(ctx, expLinks, tx)
+	shortLinks, err := c.repo.DeleteExpiredShortLinks(ctx, expLinks, tx)
+	if err != nil {
+		c.logger.Error(err.Error())
+		return
+	}
+
+	links, err := c.repo.DeleteExpiredOriginalLinks(ctx, tx)
 	if err != nil {
 		c.logger.Error(err.Error())
 		return
 	}
 
-	err = c.repo.DeleteExpiredOriginalLinks(ctx, tx)
+	idempotencyKeys, err := c.repo.DeleteExpiredIdempotencyKeys(ctx, tx)
 	if err != nil {
 		c.logger.Error(err.Error())
 		return
@@ -88,9 +100,17 @@ func (c *Cleaner) runCleanup() {
 	if err := tx.Commit(); err != nil {
 		c.logger.Error("Failed to commit transaction",
 			logger.ErrorField(err))
+		return
 	}
 
-	c.logger.Info("cleanup finished")
+	metrics.CleanerDeletedRows.WithLabelValues("short_links").Add(float64(shortLinks))
+	metrics.CleanerDeletedRows.WithLabelValues("links").Add(float64(links))
+	metrics.CleanerDeletedRows.WithLabelValues("idempotency_keys").Add(float64(idempotencyKeys))
+
+	c.logger.Info("cleanup finished",
+		logger.Int("shortLinks", int(shortLinks)),
+		logger.Int("links", int(links)),
+		logger.Int("idempotencyKeys", int(idempotencyKeys)))
 }
 
 func (c *Cleaner) Stop() {
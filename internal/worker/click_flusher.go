@@ -0,0 +1,126 @@
+package worker
+
+import (
+	"context"
+	"short_link/internal/logger"
+	"short_link/internal/model"
+	"short_link/internal/repository/cache"
+	"short_link/internal/repository/database"
+	"time"
+)
+
+type ClickFlusherConfig struct {
+	Interval  time.Duration
+	BatchSize int
+}
+
+// ClickFlusher moves click counters collected in Redis by cached redirects
+// into short_links. Counters that fail to be stored are put back into Redis
+// and retried on the next run.
+type ClickFlusher struct {
+	repo     *database.LinkRepository
+	cache    *cache.RedisClient
+	logger   *logger.Logger
+	config   ClickFlusherConfig
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+func NewClickFlusher(repo *database.LinkRepository, cache *cache.RedisClient, logger *logger.Logger, config ClickFlusherConfig) *ClickFlusher {
+	return &ClickFlusher{
+		repo:     repo,
+		cache:    cache,
+		logger:   logger,
+		config:   config,
+		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (f *ClickFlusher) Start() {
+	f.logger.Info("starting click flusher...")
+
+	ticker := time.NewTicker(f.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			f.flushAll()
+		case <-f.stopChan:
+			f.flushAll()
+			f.logger.Info("click flusher stoped")
+			close(f.done)
+			return
+		}
+	}
+}
+
+// flushAll flushes batches until no pending counters are left.
+func (f *ClickFlusher) flushAll() {
+	for {
+		flushed, ok := f.flush()
+		if !ok || flushed < f.config.BatchSize {
+			return
+		}
+	}
+}
+
+// flush stores one batch of counters. It reports how many counters were
+// taken from Redis and whether they were stored.
+func (f *ClickFlusher) flush() (int, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	counters, err := f.cache.DrainClickCounters(ctx, f.config.BatchSize)
+	if err != nil {
+		f.logger.Error(err.Error())
+		return 0, false
+	}
+
+	if len(counters) == 0 {
+		return 0, true
+	}
+
+	if err := f.store(ctx, counters); err != nil {
+		f.logger.Error(err.Error(), logger.Int("counters", len(counters)))
+		f.restore(counters)
+		return len(counters), false
+	}
+
+	return len(counters), true
+}
+
+func (f *ClickFlusher) store(ctx context.Context, counters []model.ClickCounter) error {
+	tx, err := f.repo.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := f.repo.AddAccessedCounts(ctx, tx, counters); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			f.logger.Error("Failed to rollback transaction",
+				logger.ErrorField(rbErr))
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (f *ClickFlusher) restore(counters []model.ClickCounter) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := f.cache.RestoreClickCounters(ctx, counters); err != nil {
+		f.logger.Error("Failed to restore click counters, clicks are lost",
+			logger.Int("counters", len(counters)),
+			logger.ErrorField(err))
+	}
+}
+
+// Stop flushes the pending counters and waits until they are stored.
+func (f *ClickFlusher) Stop() {
+	close(f.stopChan)
+	<-f.done
+}
@@ -0,0 +1,145 @@
+package worker
+
+import (
+	"context"
+	"short_link/internal/logger"
+	"short_link/internal/model"
+	"short_link/internal/repository/database"
+	"sync/atomic"
+	"time"
+)
+
+type ClickWriterConfig struct {
+	QueueSize     int
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// ClickWriter stores click events in the background, so the redirect path
+// never waits for Postgres. Events are queued in a bounded channel and
+// written in batches; when the queue is full new events are dropped.
+type ClickWriter struct {
+	repo     *database.LinkRepository
+	logger   *logger.Logger
+	config   ClickWriterConfig
+	events   chan model.ClickEvent
+	dropped  atomic.Int64
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+func NewClickWriter(repo *database.LinkRepository, logger *logger.Logger, config ClickWriterConfig) *ClickWriter {
+	return &ClickWriter{
+		repo:     repo,
+		logger:   logger,
+		config:   config,
+		events:   make(chan model.ClickEvent, config.QueueSize),
+		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Record queues an event without blocking. It reports false when the event
+// was dropped because the queue is full.
+func (w *ClickWriter) Record(event model.ClickEvent) bool {
+	select {
+	case w.events <- event:
+		return true
+	default:
+		w.dropped.Add(1)
+		return false
+	}
+}
+
+func (w *ClickWriter) Start() {
+	w.logger.Info("starting click writer...")
+
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]model.ClickEvent, 0, w.config.BatchSize)
+
+	for {
+		select {
+		case event := <-w.events:
+			batch = append(batch, event)
+			if len(batch) >= w.config.BatchSize {
+				batch = w.flush(batch)
+			}
+		case <-ticker.C:
+			batch = w.flush(batch)
+		case <-w.stopChan:
+			batch = w.drain(batch)
+			w.flush(batch)
+			w.logger.Info("click writer stoped")
+			close(w.done)
+			return
+		}
+	}
+}
+
+// drain moves the events still waiting in the queue into the batch, flushing
+// whenever it fills up.
+func (w *ClickWriter) drain(batch []model.ClickEvent) []model.ClickEvent {
+	for {
+		select {
+		case event := <-w.events:
+			batch = append(batch, event)
+			if len(batch) >= w.config.BatchSize {
+				batch = w.flush(batch)
+			}
+		default:
+			return batch
+		}
+	}
+}
+
+// flush writes the batch and returns it emptied for reuse. A failed batch is
+// logged and discarded: click events are best effort.
+func (w *ClickWriter) flush(batch []model.ClickEvent) []model.ClickEvent {
+	if dropped := w.dropped.Swap(0); dropped > 0 {
+		w.logger.Error("click queue is full, events dropped", logger.Int("dropped", int(dropped)))
+	}
+
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := w.repo.BeginTx(ctx)
+	if err != nil {
+		w.logger.Error("Failed to begin transaction",
+			logger.ErrorField(err))
+		return batch[:0]
+	}
+
+	err = w.repo.InsertClickEvents(ctx, tx, batch)
+	if err == nil {
+		err = w.repo.AddReferrerCounts(ctx, tx, batch)
+	}
+
+	if err != nil {
+		w.logger.Error(err.Error(), logger.Int("events", len(batch)))
+
+		if rbErr := tx.Rollback(); rbErr != nil {
+			w.logger.Error("Failed to rollback transaction",
+				logger.ErrorField(rbErr))
+		}
+		return batch[:0]
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.logger.Error("Failed to commit transaction",
+			logger.ErrorField(err))
+	}
+
+	return batch[:0]
+}
+
+// Stop flushes the queued events and waits until they are written.
+func (w *ClickWriter) Stop() {
+	close(w.stopChan)
+	<-w.done
+}
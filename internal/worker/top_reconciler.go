@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"context"
+	"short_link/internal/logger"
+	"short_link/internal/repository/cache"
+	"short_link/internal/repository/database"
+	"time"
+)
+
+type TopReconcilerConfig struct {
+	Interval time.Duration
+	// Lookback is how far back hourly leaderboard buckets are reconciled.
+	Lookback time.Duration
+	// Settle keeps the reconciler away from hours whose click events may
+	// still be queued in the click writer.
+	Settle time.Duration
+}
+
+// TopReconciler merges the click events in Postgres into the hourly
+// leaderboard sets in Redis. It repairs counts lost while Redis was
+// unavailable or restarted without persistence, and never lowers a score.
+type TopReconciler struct {
+	repo     *database.LinkRepository
+	cache    *cache.RedisClient
+	logger   *logger.Logger
+	config   TopReconcilerConfig
+	stopChan chan struct{}
+}
+
+func NewTopReconciler(repo *database.LinkRepository, cache *cache.RedisClient, logger *logger.Logger, config TopReconcilerConfig) *TopReconciler {
+	return &TopReconciler{
+		repo:     repo,
+		cache:    cache,
+		logger:   logger,
+		config:   config,
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (t *TopReconciler) Start() {
+	t.logger.Info("starting leaderboard reconciler...")
+
+	t.reconcile()
+
+	ticker := time.NewTicker(t.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.reconcile()
+		case <-t.stopChan:
+			t.logger.Info("leaderboard reconciler stoped")
+			return
+		}
+	}
+}
+
+// reconcile merges the counts from Postgres into every finished hour of the
+// lookback, keeping the higher of the two for each link.
+func (t *TopReconciler) reconcile() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	now := time.Now().UTC()
+	to := now.Add(-t.config.Settle).Truncate(time.Hour)
+	from := now.Add(-t.config.Lookback).Truncate(time.Hour)
+
+	tx, err := t.repo.BeginTx(ctx)
+	if err != nil {
+		t.logger.Error("Failed to begin transaction",
+			logger.ErrorField(err))
+		return
+	}
+
+	counts, err := t.repo.CountHumanClicksByHour(ctx, tx, from, to)
+
+	if rbErr := tx.Rollback(); rbErr != nil {
+		t.logger.Error("Failed to rollback transaction",
+			logger.ErrorField(rbErr))
+	}
+
+	if err != nil {
+		t.logger.Error(err.Error())
+		return
+	}
+
+	for hour := from; hour.Before(to); hour = hour.Add(time.Hour) {
+		if err := t.cache.MergeTopHour(ctx, hour, counts[hour]); err != nil {
+			t.logger.Error("Failed to reconcile leaderboard hour",
+				logger.String("hour", hour.Format(time.RFC3339)),
+				logger.ErrorField(err))
+			return
+		}
+	}
+
+	t.logger.Info("leaderboard reconciled")
+}
+
+func (t *TopReconciler) Stop() {
+	close(t.stopChan)
+}
@@ -0,0 +1 @@
+ALTER TABLE short_links ALTER COLUMN short_url TYPE VARCHAR(6);
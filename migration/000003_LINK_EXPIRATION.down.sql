@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_short_links_expires_at;
+
+ALTER TABLE short_links DROP COLUMN IF EXISTS expires_at;
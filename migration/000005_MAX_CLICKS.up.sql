@@ -0,0 +1 @@
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS max_clicks INTEGER DEFAULT NULL CHECK (max_clicks > 0);
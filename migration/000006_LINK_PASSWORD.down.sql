@@ -0,0 +1 @@
+ALTER TABLE short_links DROP COLUMN IF EXISTS password_hash;
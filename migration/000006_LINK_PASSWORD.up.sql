@@ -0,0 +1 @@
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS password_hash TEXT DEFAULT NULL;
@@ -0,0 +1 @@
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS active_from TIMESTAMPTZ DEFAULT NULL;
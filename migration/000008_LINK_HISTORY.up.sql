@@ -0,0 +1,12 @@
+-- links.url is shared by every short link with the same destination, so an edit
+-- repoints short_links.id_url instead of changing links.url. History keeps the
+-- urls themselves because unreferenced links rows are removed by the cleaner.
+CREATE TABLE IF NOT EXISTS short_link_history (
+    id SERIAL PRIMARY KEY,
+    short_link_id INTEGER NOT NULL REFERENCES short_links(id) ON DELETE CASCADE,
+    old_url TEXT NOT NULL,
+    new_url TEXT NOT NULL,
+    changed_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_short_link_history_short_link_id ON short_link_history(short_link_id, changed_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_short_links_deleted_at;
+
+ALTER TABLE short_links DROP COLUMN IF EXISTS disabled;
+ALTER TABLE short_links DROP COLUMN IF EXISTS deleted_at;
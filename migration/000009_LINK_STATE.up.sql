@@ -0,0 +1,4 @@
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ DEFAULT NULL;
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_short_links_deleted_at ON short_links(deleted_at) WHERE deleted_at IS NOT NULL;
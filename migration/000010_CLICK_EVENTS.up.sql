@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS click_events (
+    id BIGSERIAL PRIMARY KEY,
+    short_link_id INTEGER NOT NULL REFERENCES short_links(id) ON DELETE CASCADE,
+    clicked_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    referrer TEXT DEFAULT NULL,
+    user_agent TEXT DEFAULT NULL,
+    ip_hash CHAR(64) DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_click_events_short_link_id_clicked_at ON click_events(short_link_id, clicked_at);
@@ -0,0 +1,3 @@
+ALTER TABLE IF EXISTS click_events DROP COLUMN IF EXISTS browser_family;
+ALTER TABLE IF EXISTS click_events DROP COLUMN IF EXISTS os_family;
+ALTER TABLE IF EXISTS click_events DROP COLUMN IF EXISTS device_type;
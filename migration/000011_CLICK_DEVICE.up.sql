@@ -0,0 +1,3 @@
+ALTER TABLE click_events ADD COLUMN IF NOT EXISTS device_type VARCHAR(16) DEFAULT NULL;
+ALTER TABLE click_events ADD COLUMN IF NOT EXISTS os_family VARCHAR(32) DEFAULT NULL;
+ALTER TABLE click_events ADD COLUMN IF NOT EXISTS browser_family VARCHAR(32) DEFAULT NULL;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS referrer_daily CASCADE;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS referrer_daily (
+    short_link_id INTEGER NOT NULL REFERENCES short_links(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    domain VARCHAR(255) NOT NULL,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (short_link_id, day, domain)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS click_events DROP COLUMN IF EXISTS is_bot;
+ALTER TABLE IF EXISTS short_links DROP COLUMN IF EXISTS bot_count;
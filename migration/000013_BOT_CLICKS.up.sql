@@ -0,0 +1,2 @@
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS bot_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE click_events ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_click_events_clicked_at ON click_events(clicked_at);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_short_links_accessed_at_id;
+DROP INDEX IF EXISTS idx_short_links_accessed_count_id;
+DROP INDEX IF EXISTS idx_short_links_created_at_id;
+DROP INDEX IF EXISTS idx_links_host;
+
+ALTER TABLE IF EXISTS links DROP COLUMN IF EXISTS host;
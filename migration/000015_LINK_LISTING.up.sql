@@ -0,0 +1,9 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS host TEXT GENERATED ALWAYS AS (
+    regexp_replace(lower(substring(url FROM '^[a-zA-Z][a-zA-Z0-9+.-]*://(?:[^@/?#]*@)?([^:/?#]+)')), '^www\.', '')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_links_host ON links(host);
+
+CREATE INDEX IF NOT EXISTS idx_short_links_created_at_id ON short_links(created_at, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_short_links_accessed_count_id ON short_links(accessed_count, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_short_links_accessed_at_id ON short_links((COALESCE(accessed_at, '-infinity'::timestamp)), id) WHERE deleted_at IS NULL;
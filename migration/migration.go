@@ -0,0 +1,41 @@
+// Package migration embeds the SQL migrations applied by golang-migrate, so
+// the app knows at build time which schema version it needs.
+package migration
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.up.sql
+var files embed.FS
+
+// Latest returns the version of the newest migration, the number its file
+// name starts with.
+func Latest() (uint64, error) {
+	names, err := fs.Glob(files, "*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint64
+
+	for _, name := range names {
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return 0, fmt.Errorf("migration %s has no version", name)
+		}
+
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s has no version: %w", name, err)
+		}
+
+		latest = max(latest, version)
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,37 @@
+package migration
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestLatest(t *testing.T) {
+	latest, err := Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ups, err := filepath.Glob("*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// golang-migrate needs the versions without gaps and a down file for
+	// every up file.
+	if latest != uint64(len(ups)) {
+		t.Errorf("Latest() = %d, but there are %d migrations", latest, len(ups))
+	}
+
+	for version := uint64(1); version <= latest; version++ {
+		for _, suffix := range []string{".up.sql", ".down.sql"} {
+			matches, err := filepath.Glob(fmt.Sprintf("%06d_*%s", version, suffix))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(matches) != 1 {
+				t.Errorf("want one %06d_*%s file, found %d", version, suffix, len(matches))
+			}
+		}
+	}
+}